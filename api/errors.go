@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/stevenvegt/pseudonyms/auth"
+	domain "github.com/stevenvegt/pseudonyms/domain"
+	"github.com/stevenvegt/pseudonyms/keys"
+	"github.com/stevenvegt/pseudonyms/policy"
+	"github.com/stevenvegt/pseudonyms/registry"
+	"github.com/stevenvegt/pseudonyms/replay"
+)
+
+var (
+	// ErrInvalidRequest is returned when a request misses a required field or contains an invalid value.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrInvalidToken is returned when a token can not be used, e.g. because it is expired or issued for another organisation.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrAudienceRevoked is returned when pseudonyms are requested for an audience of which the key has been revoked.
+	ErrAudienceRevoked = errors.New("pseudonym key of audience has been revoked")
+	// ErrOrganisationMismatch is returned when the organisation in a request is not the authenticated organisation of the caller.
+	ErrOrganisationMismatch = errors.New("organisation does not match the authenticated organisation")
+	// ErrScopeNotGranted is returned when a caller requests a scope which is not granted to it.
+	ErrScopeNotGranted = errors.New("scope is not granted to the caller")
+	// ErrRevocationNotPermitted is returned when a token is revoked by an organisation which is not its issuer or audience.
+	ErrRevocationNotPermitted = errors.New("only the issuer or audience of a token can revoke it")
+	// ErrAdminNotPermitted is returned when an organisation uses an administrative endpoint without being an
+	// administrator in the registry.
+	ErrAdminNotPermitted = errors.New("organisation may not use the administrative endpoints")
+	// ErrPseudonymSuperseded is returned when the version of a pseudonym has been bumped after it was created.
+	ErrPseudonymSuperseded = errors.New("pseudonym has been superseded by a new version")
+)
+
+// problemStatus returns the HTTP status of an error, errors which are not known are internal server errors.
+func problemStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidRequest),
+		errors.Is(err, domain.ErrStudyRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidToken),
+		errors.Is(err, auth.ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrAudienceRevoked),
+		errors.Is(err, ErrOrganisationMismatch),
+		errors.Is(err, ErrScopeNotGranted),
+		errors.Is(err, ErrRevocationNotPermitted),
+		errors.Is(err, ErrAdminNotPermitted),
+		errors.Is(err, registry.ErrUnknownOrganisation),
+		errors.Is(err, registry.ErrNotAllowed),
+		errors.Is(err, policy.ErrDenied),
+		errors.Is(err, domain.ErrIrreversiblePseudonym),
+		errors.Is(err, domain.ErrResearchScope):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrMalformed),
+		errors.Is(err, domain.ErrDecryptionFailed),
+		errors.Is(err, domain.ErrCorruptedIdentifier),
+		errors.Is(err, domain.ErrWrongContentType),
+		errors.Is(err, domain.ErrUnsupportedVersion),
+		errors.Is(err, keys.ErrUnknownKey),
+		errors.Is(err, ErrPseudonymSuperseded):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, replay.ErrCacheFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeProblem writes an error as problem details of RFC 7807.
+// The details of internal server errors are logged, not returned to the client.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, err error) {
+	title := http.StatusText(status)
+	detail := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		detail = title
+	}
+	instance := r.URL.Path
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(Problem{
+		Title:    &title,
+		Status:   &status,
+		Detail:   &detail,
+		Instance: &instance,
+	})
+}
+
+// WriteError writes an error as problem details, with a status code based on the type of the error.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	writeProblem(w, r, problemStatus(err), err)
+}
+
+// ProblemHandlerOptions returns the options of the strict handler which write all errors as problem details.
+// Errors returned by the service are mapped to a status code based on their type.
+func ProblemHandlerOptions() StrictHTTPServerOptions {
+	return StrictHTTPServerOptions{
+		RequestErrorHandlerFunc: func(w http.ResponseWriter, r *http.Request, err error) {
+			writeProblem(w, r, http.StatusBadRequest, err)
+		},
+		ResponseErrorHandlerFunc: WriteError,
+	}
+}
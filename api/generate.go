@@ -6,20 +6,43 @@
 package api
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"path"
+	"strings"
 
+	"github.com/getkin/kin-openapi/openapi3"
 	strictnethttp "github.com/oapi-codegen/runtime/strictmiddleware/nethttp"
 )
 
 // Defines values for IdentifierTypes.
 const (
 	BSN                IdentifierTypes = "BSN"
+	ENCRYPTEDPSEUDO    IdentifierTypes = "ENCRYPTED_PSEUDO"
 	ORGANISATIONPSEUDO IdentifierTypes = "ORGANISATION_PSEUDO"
+	POLYMORPHICPSEUDO  IdentifierTypes = "POLYMORPHIC_PSEUDO"
+	RESEARCHPSEUDO     IdentifierTypes = "RESEARCH_PSEUDO"
 )
 
+// Defines values for Scope.
+const (
+	Onderzoek Scope = "onderzoek"
+	Zorg      Scope = "zorg"
+)
+
+// BumpPseudonymVersionResponse defines model for bumpPseudonymVersionResponse.
+type BumpPseudonymVersionResponse struct {
+	// Version new version of the pseudonyms, pseudonyms with an older version are rejected
+	Version int32 `json:"version"`
+}
+
 // ExchangeIdentifierResponse defines model for exchangeIdentifierResponse.
 type ExchangeIdentifierResponse struct {
 	Identifier *Identifier `json:"identifier,omitempty"`
@@ -30,6 +53,16 @@ type ExchangeTokenResponse struct {
 	Identifier *Identifier `json:"identifier,omitempty"`
 }
 
+// ExplainPolicyResponse defines model for explainPolicyResponse.
+type ExplainPolicyResponse struct {
+	// Allowed whether the exchange is permitted
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+
+	// Rule name of the rule which decided, absent when the default of the policy was applied
+	Rule *string `json:"rule,omitempty"`
+}
+
 // GetTokenResponse defines model for getTokenResponse.
 type GetTokenResponse struct {
 	Token *Token `json:"token,omitempty"`
@@ -37,67 +70,272 @@ type GetTokenResponse struct {
 
 // Identifier defines model for identifier.
 type Identifier struct {
-	Type  *IdentifierTypes `json:"type,omitempty"`
-	Value *string          `json:"value,omitempty"`
+	Type  IdentifierTypes `json:"type"`
+	Value string          `json:"value"`
 }
 
 // IdentifierTypes defines model for identifierTypes.
 type IdentifierTypes string
 
-// Scope defines model for scope.
-type Scope = string
+// OprfEvaluateResponse defines model for oprfEvaluateResponse.
+type OprfEvaluateResponse struct {
+	// EvaluatedElement base64 encoded ristretto255 element, to be finalized by the client
+	EvaluatedElement *[]byte `json:"evaluatedElement,omitempty"`
+
+	// KeyId ID of the pseudonym key the element was evaluated with
+	KeyId *string `json:"keyId,omitempty"`
+}
+
+// Problem problem details of an error as described in RFC 7807
+type Problem struct {
+	// Detail explanation of this occurrence of the problem
+	Detail *string `json:"detail,omitempty"`
+
+	// Instance URI reference which identifies this occurrence of the problem
+	Instance *string `json:"instance,omitempty"`
+
+	// Status HTTP status code of the response
+	Status *int `json:"status,omitempty"`
+
+	// Title short summary of the problem type
+	Title *string `json:"title,omitempty"`
+
+	// Type URI reference which identifies the problem type
+	Type *string `json:"type,omitempty"`
+}
+
+// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+type Scope string
+
+// Study identifier of the research study, required for research pseudonyms
+type Study = string
 
 // Token defines model for token.
 type Token = string
 
+// BadRequest problem details of an error as described in RFC 7807
+type BadRequest = Problem
+
+// Forbidden problem details of an error as described in RFC 7807
+type Forbidden = Problem
+
+// Unauthorized problem details of an error as described in RFC 7807
+type Unauthorized = Problem
+
+// Unprocessable problem details of an error as described in RFC 7807
+type Unprocessable = Problem
+
+// BumpPseudonymVersionRequest defines model for bumpPseudonymVersionRequest.
+type BumpPseudonymVersionRequest struct {
+	// Audience organisation of which the pseudonyms are bumped
+	Audience   string     `json:"audience"`
+	Identifier Identifier `json:"identifier"`
+
+	// Organisation organisation of the administrator, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope *Scope `json:"scope,omitempty"`
+}
+
 // ExchangeIdentifierRequest defines model for exchangeIdentifierRequest.
 type ExchangeIdentifierRequest struct {
-	Identifier              *Identifier      `json:"identifier,omitempty"`
-	Organisation            *string          `json:"organisation,omitempty"`
-	RecipientIdentifierType *IdentifierTypes `json:"recipientIdentifierType,omitempty"`
-	Scope                   *Scope           `json:"scope,omitempty"`
+	Identifier Identifier `json:"identifier"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation            *string         `json:"organisation,omitempty"`
+	RecipientIdentifierType IdentifierTypes `json:"recipientIdentifierType"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Study identifier of the research study, required for research pseudonyms
+	Study *Study `json:"study,omitempty"`
 }
 
 // ExchangeTokenRequest defines model for exchangeTokenRequest.
 type ExchangeTokenRequest struct {
-	IdentifierType *IdentifierTypes `json:"identifierType,omitempty"`
-	Organisation   *string          `json:"organisation,omitempty"`
-	Scope          *Scope           `json:"scope,omitempty"`
-	Token          *Token           `json:"token,omitempty"`
+	IdentifierType IdentifierTypes `json:"identifierType"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Study identifier of the research study, required for research pseudonyms
+	Study *Study `json:"study,omitempty"`
+	Token Token  `json:"token"`
+}
+
+// ExplainPolicyRequest defines model for explainPolicyRequest.
+type ExplainPolicyRequest struct {
+	// Audience organisation which receives the result of the exchange, the caller when absent
+	Audience *string `json:"audience,omitempty"`
+
+	// Caller organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Caller *string `json:"caller,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// SourceType identifier type which is exchanged, or TOKEN for a token exchange
+	SourceType string `json:"sourceType"`
+
+	// TargetType identifier type which is requested, TOKEN for a token request or OPRF_PSEUDO for an OPRF evaluation
+	TargetType string `json:"targetType"`
 }
 
 // GetTokenRequest defines model for getTokenRequest.
 type GetTokenRequest struct {
-	Identifier *Identifier `json:"identifier,omitempty"`
-	Receiver   *string     `json:"receiver,omitempty"`
-	Scope      *Scope      `json:"scope,omitempty"`
-	Sender     *string     `json:"sender,omitempty"`
+	Identifier Identifier `json:"identifier"`
+	Receiver   string     `json:"receiver"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Sender organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Sender *string `json:"sender,omitempty"`
+
+	// SingleUse the token can only be exchanged once, tokens are always single-use when the service requires it
+	SingleUse *bool `json:"singleUse,omitempty"`
+}
+
+// OprfEvaluateRequest defines model for oprfEvaluateRequest.
+type OprfEvaluateRequest struct {
+	// BlindedElement base64 encoded ristretto255 element of the blinded identifier (RFC 9497, ristretto255-SHA512)
+	BlindedElement []byte `json:"blindedElement"`
+
+	// KeyId ID of the pseudonym key to evaluate with, the active key when absent. The pseudonym of an identifier changes with the key, clients evaluate with the key ID of their earlier pseudonyms to find them after a key rotation
+	KeyId *string `json:"keyId,omitempty"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+}
+
+// RevokeIssuerRequest defines model for revokeIssuerRequest.
+type RevokeIssuerRequest struct {
+	// Issuer organisation which issued the tokens, it must match the authenticated organisation of the caller
+	Issuer string `json:"issuer"`
+}
+
+// RevokeTokenRequest defines model for revokeTokenRequest.
+type RevokeTokenRequest struct {
+	// Organisation issuer or audience of the token, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+	Token        Token   `json:"token"`
+}
+
+// BumpPseudonymVersionJSONBody defines parameters for BumpPseudonymVersion.
+type BumpPseudonymVersionJSONBody struct {
+	// Audience organisation of which the pseudonyms are bumped
+	Audience   string     `json:"audience"`
+	Identifier Identifier `json:"identifier"`
+
+	// Organisation organisation of the administrator, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope *Scope `json:"scope,omitempty"`
 }
 
 // ExchangeIdentifierJSONBody defines parameters for ExchangeIdentifier.
 type ExchangeIdentifierJSONBody struct {
-	Identifier              *Identifier      `json:"identifier,omitempty"`
-	Organisation            *string          `json:"organisation,omitempty"`
-	RecipientIdentifierType *IdentifierTypes `json:"recipientIdentifierType,omitempty"`
-	Scope                   *Scope           `json:"scope,omitempty"`
+	Identifier Identifier `json:"identifier"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation            *string         `json:"organisation,omitempty"`
+	RecipientIdentifierType IdentifierTypes `json:"recipientIdentifierType"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Study identifier of the research study, required for research pseudonyms
+	Study *Study `json:"study,omitempty"`
 }
 
 // ExchangeTokenJSONBody defines parameters for ExchangeToken.
 type ExchangeTokenJSONBody struct {
-	IdentifierType *IdentifierTypes `json:"identifierType,omitempty"`
-	Organisation   *string          `json:"organisation,omitempty"`
-	Scope          *Scope           `json:"scope,omitempty"`
-	Token          *Token           `json:"token,omitempty"`
+	IdentifierType IdentifierTypes `json:"identifierType"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Study identifier of the research study, required for research pseudonyms
+	Study *Study `json:"study,omitempty"`
+	Token Token  `json:"token"`
 }
 
 // GetTokenJSONBody defines parameters for GetToken.
 type GetTokenJSONBody struct {
-	Identifier *Identifier `json:"identifier,omitempty"`
-	Receiver   *string     `json:"receiver,omitempty"`
-	Scope      *Scope      `json:"scope,omitempty"`
-	Sender     *string     `json:"sender,omitempty"`
+	Identifier Identifier `json:"identifier"`
+	Receiver   string     `json:"receiver"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// Sender organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Sender *string `json:"sender,omitempty"`
+
+	// SingleUse the token can only be exchanged once, tokens are always single-use when the service requires it
+	SingleUse *bool `json:"singleUse,omitempty"`
+}
+
+// OprfEvaluateJSONBody defines parameters for OprfEvaluate.
+type OprfEvaluateJSONBody struct {
+	// BlindedElement base64 encoded ristretto255 element of the blinded identifier (RFC 9497, ristretto255-SHA512)
+	BlindedElement []byte `json:"blindedElement"`
+
+	// KeyId ID of the pseudonym key to evaluate with, the active key when absent. The pseudonym of an identifier changes with the key, clients evaluate with the key ID of their earlier pseudonyms to find them after a key rotation
+	KeyId *string `json:"keyId,omitempty"`
+
+	// Organisation organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+}
+
+// ExplainPolicyJSONBody defines parameters for ExplainPolicy.
+type ExplainPolicyJSONBody struct {
+	// Audience organisation which receives the result of the exchange, the caller when absent
+	Audience *string `json:"audience,omitempty"`
+
+	// Caller organisation of the caller, when mutual TLS is used it must match the organisation of the client certificate
+	Caller *string `json:"caller,omitempty"`
+
+	// Scope purpose the identifier is used for, zorg (treatment) or onderzoek (research)
+	Scope Scope `json:"scope"`
+
+	// SourceType identifier type which is exchanged, or TOKEN for a token exchange
+	SourceType string `json:"sourceType"`
+
+	// TargetType identifier type which is requested, TOKEN for a token request or OPRF_PSEUDO for an OPRF evaluation
+	TargetType string `json:"targetType"`
+}
+
+// RevokeIssuerJSONBody defines parameters for RevokeIssuer.
+type RevokeIssuerJSONBody struct {
+	// Issuer organisation which issued the tokens, it must match the authenticated organisation of the caller
+	Issuer string `json:"issuer"`
+}
+
+// RevokeTokenJSONBody defines parameters for RevokeToken.
+type RevokeTokenJSONBody struct {
+	// Organisation issuer or audience of the token, when mutual TLS is used it must match the organisation of the client certificate
+	Organisation *string `json:"organisation,omitempty"`
+	Token        Token   `json:"token"`
 }
 
+// BumpPseudonymVersionJSONRequestBody defines body for BumpPseudonymVersion for application/json ContentType.
+type BumpPseudonymVersionJSONRequestBody BumpPseudonymVersionJSONBody
+
 // ExchangeIdentifierJSONRequestBody defines body for ExchangeIdentifier for application/json ContentType.
 type ExchangeIdentifierJSONRequestBody ExchangeIdentifierJSONBody
 
@@ -107,8 +345,23 @@ type ExchangeTokenJSONRequestBody ExchangeTokenJSONBody
 // GetTokenJSONRequestBody defines body for GetToken for application/json ContentType.
 type GetTokenJSONRequestBody GetTokenJSONBody
 
+// OprfEvaluateJSONRequestBody defines body for OprfEvaluate for application/json ContentType.
+type OprfEvaluateJSONRequestBody OprfEvaluateJSONBody
+
+// ExplainPolicyJSONRequestBody defines body for ExplainPolicy for application/json ContentType.
+type ExplainPolicyJSONRequestBody ExplainPolicyJSONBody
+
+// RevokeIssuerJSONRequestBody defines body for RevokeIssuer for application/json ContentType.
+type RevokeIssuerJSONRequestBody RevokeIssuerJSONBody
+
+// RevokeTokenJSONRequestBody defines body for RevokeToken for application/json ContentType.
+type RevokeTokenJSONRequestBody RevokeTokenJSONBody
+
 // ServerInterface represents all server handlers.
 type ServerInterface interface {
+	// bump the version of the pseudonyms of a subject for an audience, after a pseudonym has been compromised
+	// (POST /admin/bumpPseudonymVersion)
+	BumpPseudonymVersion(w http.ResponseWriter, r *http.Request)
 	// exchange an identifier for another identifier
 	// (POST /exchangeIdentifier)
 	ExchangeIdentifier(w http.ResponseWriter, r *http.Request)
@@ -118,6 +371,18 @@ type ServerInterface interface {
 	// get a token
 	// (POST /getToken)
 	GetToken(w http.ResponseWriter, r *http.Request)
+	// evaluate a blinded identifier with the OPRF key of an organisation
+	// (POST /oprf/evaluate)
+	OprfEvaluate(w http.ResponseWriter, r *http.Request)
+	// explain whether the policy permits an exchange, without performing it
+	// (POST /policy/explain)
+	ExplainPolicy(w http.ResponseWriter, r *http.Request)
+	// revoke all tokens issued by an organisation until now, the organisation must be authenticated
+	// (POST /revokeIssuer)
+	RevokeIssuer(w http.ResponseWriter, r *http.Request)
+	// revoke a token, it can not be exchanged anymore
+	// (POST /revokeToken)
+	RevokeToken(w http.ResponseWriter, r *http.Request)
 }
 
 // ServerInterfaceWrapper converts contexts to parameters.
@@ -129,6 +394,20 @@ type ServerInterfaceWrapper struct {
 
 type MiddlewareFunc func(http.Handler) http.Handler
 
+// BumpPseudonymVersion operation middleware
+func (siw *ServerInterfaceWrapper) BumpPseudonymVersion(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.BumpPseudonymVersion(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 // ExchangeIdentifier operation middleware
 func (siw *ServerInterfaceWrapper) ExchangeIdentifier(w http.ResponseWriter, r *http.Request) {
 
@@ -171,6 +450,62 @@ func (siw *ServerInterfaceWrapper) GetToken(w http.ResponseWriter, r *http.Reque
 	handler.ServeHTTP(w, r)
 }
 
+// OprfEvaluate operation middleware
+func (siw *ServerInterfaceWrapper) OprfEvaluate(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.OprfEvaluate(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// ExplainPolicy operation middleware
+func (siw *ServerInterfaceWrapper) ExplainPolicy(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.ExplainPolicy(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RevokeIssuer operation middleware
+func (siw *ServerInterfaceWrapper) RevokeIssuer(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RevokeIssuer(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// RevokeToken operation middleware
+func (siw *ServerInterfaceWrapper) RevokeToken(w http.ResponseWriter, r *http.Request) {
+
+	handler := http.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		siw.Handler.RevokeToken(w, r)
+	}))
+
+	for _, middleware := range siw.HandlerMiddlewares {
+		handler = middleware(handler)
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
 type UnescapedCookieParamError struct {
 	ParamName string
 	Err       error
@@ -291,19 +626,116 @@ func HandlerWithOptions(si ServerInterface, options StdHTTPServerOptions) http.H
 		ErrorHandlerFunc:   options.ErrorHandlerFunc,
 	}
 
+	m.HandleFunc("POST "+options.BaseURL+"/admin/bumpPseudonymVersion", wrapper.BumpPseudonymVersion)
 	m.HandleFunc("POST "+options.BaseURL+"/exchangeIdentifier", wrapper.ExchangeIdentifier)
 	m.HandleFunc("POST "+options.BaseURL+"/exchangeToken", wrapper.ExchangeToken)
 	m.HandleFunc("POST "+options.BaseURL+"/getToken", wrapper.GetToken)
+	m.HandleFunc("POST "+options.BaseURL+"/oprf/evaluate", wrapper.OprfEvaluate)
+	m.HandleFunc("POST "+options.BaseURL+"/policy/explain", wrapper.ExplainPolicy)
+	m.HandleFunc("POST "+options.BaseURL+"/revokeIssuer", wrapper.RevokeIssuer)
+	m.HandleFunc("POST "+options.BaseURL+"/revokeToken", wrapper.RevokeToken)
 
 	return m
 }
 
+type BadRequestApplicationProblemPlusJSONResponse Problem
+
+type BumpPseudonymVersionResponseJSONResponse BumpPseudonymVersionResponse
+
+type ExchangeIdentifierResponseApplicationcborResponse struct {
+	Body io.Reader
+
+	ContentLength int64
+}
 type ExchangeIdentifierResponseJSONResponse ExchangeIdentifierResponse
 
+type ExchangeTokenResponseApplicationcborResponse struct {
+	Body io.Reader
+
+	ContentLength int64
+}
 type ExchangeTokenResponseJSONResponse ExchangeTokenResponse
 
+type ExplainPolicyResponseJSONResponse ExplainPolicyResponse
+
+type ForbiddenApplicationProblemPlusJSONResponse Problem
+
+type GetTokenResponseApplicationcborResponse struct {
+	Body io.Reader
+
+	ContentLength int64
+}
 type GetTokenResponseJSONResponse GetTokenResponse
 
+type OprfEvaluateResponseJSONResponse OprfEvaluateResponse
+
+type UnauthorizedApplicationProblemPlusJSONResponse Problem
+
+type UnprocessableApplicationProblemPlusJSONResponse Problem
+
+type BumpPseudonymVersionRequestObject struct {
+	Body *BumpPseudonymVersionJSONRequestBody
+}
+
+type BumpPseudonymVersionResponseObject interface {
+	VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error
+}
+
+type BumpPseudonymVersion200JSONResponse struct {
+	BumpPseudonymVersionResponseJSONResponse
+}
+
+func (response BumpPseudonymVersion200JSONResponse) VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type BumpPseudonymVersion400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response BumpPseudonymVersion400ApplicationProblemPlusJSONResponse) VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type BumpPseudonymVersion401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response BumpPseudonymVersion401ApplicationProblemPlusJSONResponse) VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type BumpPseudonymVersion403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response BumpPseudonymVersion403ApplicationProblemPlusJSONResponse) VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type BumpPseudonymVersion422ApplicationProblemPlusJSONResponse struct {
+	UnprocessableApplicationProblemPlusJSONResponse
+}
+
+func (response BumpPseudonymVersion422ApplicationProblemPlusJSONResponse) VisitBumpPseudonymVersionResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(422)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 type ExchangeIdentifierRequestObject struct {
 	Body *ExchangeIdentifierJSONRequestBody
 }
@@ -312,6 +744,24 @@ type ExchangeIdentifierResponseObject interface {
 	VisitExchangeIdentifierResponse(w http.ResponseWriter) error
 }
 
+type ExchangeIdentifier200ApplicationcborResponse struct {
+	ExchangeIdentifierResponseApplicationcborResponse
+}
+
+func (response ExchangeIdentifier200ApplicationcborResponse) VisitExchangeIdentifierResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/cbor")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
 type ExchangeIdentifier200JSONResponse struct {
 	ExchangeIdentifierResponseJSONResponse
 }
@@ -323,6 +773,50 @@ func (response ExchangeIdentifier200JSONResponse) VisitExchangeIdentifierRespons
 	return json.NewEncoder(w).Encode(response)
 }
 
+type ExchangeIdentifier400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeIdentifier400ApplicationProblemPlusJSONResponse) VisitExchangeIdentifierResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExchangeIdentifier401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeIdentifier401ApplicationProblemPlusJSONResponse) VisitExchangeIdentifierResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExchangeIdentifier403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeIdentifier403ApplicationProblemPlusJSONResponse) VisitExchangeIdentifierResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExchangeIdentifier422ApplicationProblemPlusJSONResponse struct {
+	UnprocessableApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeIdentifier422ApplicationProblemPlusJSONResponse) VisitExchangeIdentifierResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(422)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 type ExchangeTokenRequestObject struct {
 	Body *ExchangeTokenJSONRequestBody
 }
@@ -331,6 +825,24 @@ type ExchangeTokenResponseObject interface {
 	VisitExchangeTokenResponse(w http.ResponseWriter) error
 }
 
+type ExchangeToken200ApplicationcborResponse struct {
+	ExchangeTokenResponseApplicationcborResponse
+}
+
+func (response ExchangeToken200ApplicationcborResponse) VisitExchangeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/cbor")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
 type ExchangeToken200JSONResponse struct {
 	ExchangeTokenResponseJSONResponse
 }
@@ -342,6 +854,39 @@ func (response ExchangeToken200JSONResponse) VisitExchangeTokenResponse(w http.R
 	return json.NewEncoder(w).Encode(response)
 }
 
+type ExchangeToken400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeToken400ApplicationProblemPlusJSONResponse) VisitExchangeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExchangeToken401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeToken401ApplicationProblemPlusJSONResponse) VisitExchangeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExchangeToken403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response ExchangeToken403ApplicationProblemPlusJSONResponse) VisitExchangeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
 type GetTokenRequestObject struct {
 	Body *GetTokenJSONRequestBody
 }
@@ -350,6 +895,24 @@ type GetTokenResponseObject interface {
 	VisitGetTokenResponse(w http.ResponseWriter) error
 }
 
+type GetToken200ApplicationcborResponse struct {
+	GetTokenResponseApplicationcborResponse
+}
+
+func (response GetToken200ApplicationcborResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/cbor")
+	if response.ContentLength != 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(response.ContentLength))
+	}
+	w.WriteHeader(200)
+
+	if closer, ok := response.Body.(io.ReadCloser); ok {
+		defer closer.Close()
+	}
+	_, err := io.Copy(w, response.Body)
+	return err
+}
+
 type GetToken200JSONResponse struct{ GetTokenResponseJSONResponse }
 
 func (response GetToken200JSONResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
@@ -359,8 +922,257 @@ func (response GetToken200JSONResponse) VisitGetTokenResponse(w http.ResponseWri
 	return json.NewEncoder(w).Encode(response)
 }
 
-// StrictServerInterface represents all server handlers.
+type GetToken400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response GetToken400ApplicationProblemPlusJSONResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetToken401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response GetToken401ApplicationProblemPlusJSONResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetToken403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response GetToken403ApplicationProblemPlusJSONResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type GetToken422ApplicationProblemPlusJSONResponse struct {
+	UnprocessableApplicationProblemPlusJSONResponse
+}
+
+func (response GetToken422ApplicationProblemPlusJSONResponse) VisitGetTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(422)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type OprfEvaluateRequestObject struct {
+	Body *OprfEvaluateJSONRequestBody
+}
+
+type OprfEvaluateResponseObject interface {
+	VisitOprfEvaluateResponse(w http.ResponseWriter) error
+}
+
+type OprfEvaluate200JSONResponse struct {
+	OprfEvaluateResponseJSONResponse
+}
+
+func (response OprfEvaluate200JSONResponse) VisitOprfEvaluateResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type OprfEvaluate400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response OprfEvaluate400ApplicationProblemPlusJSONResponse) VisitOprfEvaluateResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type OprfEvaluate401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response OprfEvaluate401ApplicationProblemPlusJSONResponse) VisitOprfEvaluateResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type OprfEvaluate403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response OprfEvaluate403ApplicationProblemPlusJSONResponse) VisitOprfEvaluateResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExplainPolicyRequestObject struct {
+	Body *ExplainPolicyJSONRequestBody
+}
+
+type ExplainPolicyResponseObject interface {
+	VisitExplainPolicyResponse(w http.ResponseWriter) error
+}
+
+type ExplainPolicy200JSONResponse struct {
+	ExplainPolicyResponseJSONResponse
+}
+
+func (response ExplainPolicy200JSONResponse) VisitExplainPolicyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExplainPolicy400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response ExplainPolicy400ApplicationProblemPlusJSONResponse) VisitExplainPolicyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExplainPolicy401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response ExplainPolicy401ApplicationProblemPlusJSONResponse) VisitExplainPolicyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type ExplainPolicy403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response ExplainPolicy403ApplicationProblemPlusJSONResponse) VisitExplainPolicyResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeIssuerRequestObject struct {
+	Body *RevokeIssuerJSONRequestBody
+}
+
+type RevokeIssuerResponseObject interface {
+	VisitRevokeIssuerResponse(w http.ResponseWriter) error
+}
+
+type RevokeIssuer204Response struct {
+}
+
+func (response RevokeIssuer204Response) VisitRevokeIssuerResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type RevokeIssuer400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeIssuer400ApplicationProblemPlusJSONResponse) VisitRevokeIssuerResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeIssuer401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeIssuer401ApplicationProblemPlusJSONResponse) VisitRevokeIssuerResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeIssuer403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeIssuer403ApplicationProblemPlusJSONResponse) VisitRevokeIssuerResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeTokenRequestObject struct {
+	Body *RevokeTokenJSONRequestBody
+}
+
+type RevokeTokenResponseObject interface {
+	VisitRevokeTokenResponse(w http.ResponseWriter) error
+}
+
+type RevokeToken204Response struct {
+}
+
+func (response RevokeToken204Response) VisitRevokeTokenResponse(w http.ResponseWriter) error {
+	w.WriteHeader(204)
+	return nil
+}
+
+type RevokeToken400ApplicationProblemPlusJSONResponse struct {
+	BadRequestApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeToken400ApplicationProblemPlusJSONResponse) VisitRevokeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(400)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeToken401ApplicationProblemPlusJSONResponse struct {
+	UnauthorizedApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeToken401ApplicationProblemPlusJSONResponse) VisitRevokeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(401)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+type RevokeToken403ApplicationProblemPlusJSONResponse struct {
+	ForbiddenApplicationProblemPlusJSONResponse
+}
+
+func (response RevokeToken403ApplicationProblemPlusJSONResponse) VisitRevokeTokenResponse(w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(403)
+
+	return json.NewEncoder(w).Encode(response)
+}
+
+// StrictServerInterface represents all server handlers.
 type StrictServerInterface interface {
+	// bump the version of the pseudonyms of a subject for an audience, after a pseudonym has been compromised
+	// (POST /admin/bumpPseudonymVersion)
+	BumpPseudonymVersion(ctx context.Context, request BumpPseudonymVersionRequestObject) (BumpPseudonymVersionResponseObject, error)
 	// exchange an identifier for another identifier
 	// (POST /exchangeIdentifier)
 	ExchangeIdentifier(ctx context.Context, request ExchangeIdentifierRequestObject) (ExchangeIdentifierResponseObject, error)
@@ -370,6 +1182,18 @@ type StrictServerInterface interface {
 	// get a token
 	// (POST /getToken)
 	GetToken(ctx context.Context, request GetTokenRequestObject) (GetTokenResponseObject, error)
+	// evaluate a blinded identifier with the OPRF key of an organisation
+	// (POST /oprf/evaluate)
+	OprfEvaluate(ctx context.Context, request OprfEvaluateRequestObject) (OprfEvaluateResponseObject, error)
+	// explain whether the policy permits an exchange, without performing it
+	// (POST /policy/explain)
+	ExplainPolicy(ctx context.Context, request ExplainPolicyRequestObject) (ExplainPolicyResponseObject, error)
+	// revoke all tokens issued by an organisation until now, the organisation must be authenticated
+	// (POST /revokeIssuer)
+	RevokeIssuer(ctx context.Context, request RevokeIssuerRequestObject) (RevokeIssuerResponseObject, error)
+	// revoke a token, it can not be exchanged anymore
+	// (POST /revokeToken)
+	RevokeToken(ctx context.Context, request RevokeTokenRequestObject) (RevokeTokenResponseObject, error)
 }
 
 type StrictHandlerFunc = strictnethttp.StrictHTTPHandlerFunc
@@ -401,6 +1225,37 @@ type strictHandler struct {
 	options     StrictHTTPServerOptions
 }
 
+// BumpPseudonymVersion operation middleware
+func (sh *strictHandler) BumpPseudonymVersion(w http.ResponseWriter, r *http.Request) {
+	var request BumpPseudonymVersionRequestObject
+
+	var body BumpPseudonymVersionJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.BumpPseudonymVersion(ctx, request.(BumpPseudonymVersionRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "BumpPseudonymVersion")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(BumpPseudonymVersionResponseObject); ok {
+		if err := validResponse.VisitBumpPseudonymVersionResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
 // ExchangeIdentifier operation middleware
 func (sh *strictHandler) ExchangeIdentifier(w http.ResponseWriter, r *http.Request) {
 	var request ExchangeIdentifierRequestObject
@@ -493,3 +1348,242 @@ func (sh *strictHandler) GetToken(w http.ResponseWriter, r *http.Request) {
 		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
 	}
 }
+
+// OprfEvaluate operation middleware
+func (sh *strictHandler) OprfEvaluate(w http.ResponseWriter, r *http.Request) {
+	var request OprfEvaluateRequestObject
+
+	var body OprfEvaluateJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.OprfEvaluate(ctx, request.(OprfEvaluateRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "OprfEvaluate")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(OprfEvaluateResponseObject); ok {
+		if err := validResponse.VisitOprfEvaluateResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// ExplainPolicy operation middleware
+func (sh *strictHandler) ExplainPolicy(w http.ResponseWriter, r *http.Request) {
+	var request ExplainPolicyRequestObject
+
+	var body ExplainPolicyJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.ExplainPolicy(ctx, request.(ExplainPolicyRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "ExplainPolicy")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(ExplainPolicyResponseObject); ok {
+		if err := validResponse.VisitExplainPolicyResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// RevokeIssuer operation middleware
+func (sh *strictHandler) RevokeIssuer(w http.ResponseWriter, r *http.Request) {
+	var request RevokeIssuerRequestObject
+
+	var body RevokeIssuerJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.RevokeIssuer(ctx, request.(RevokeIssuerRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "RevokeIssuer")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(RevokeIssuerResponseObject); ok {
+		if err := validResponse.VisitRevokeIssuerResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// RevokeToken operation middleware
+func (sh *strictHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var request RevokeTokenRequestObject
+
+	var body RevokeTokenJSONRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		sh.options.RequestErrorHandlerFunc(w, r, fmt.Errorf("can't decode JSON body: %w", err))
+		return
+	}
+	request.Body = &body
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		return sh.ssi.RevokeToken(ctx, request.(RevokeTokenRequestObject))
+	}
+	for _, middleware := range sh.middlewares {
+		handler = middleware(handler, "RevokeToken")
+	}
+
+	response, err := handler(r.Context(), w, r, request)
+
+	if err != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, err)
+	} else if validResponse, ok := response.(RevokeTokenResponseObject); ok {
+		if err := validResponse.VisitRevokeTokenResponse(w); err != nil {
+			sh.options.ResponseErrorHandlerFunc(w, r, err)
+		}
+	} else if response != nil {
+		sh.options.ResponseErrorHandlerFunc(w, r, fmt.Errorf("unexpected response type: %T", response))
+	}
+}
+
+// Base64 encoded, gzipped, json marshaled Swagger object
+var swaggerSpec = []string{
+
+	"H4sIAAAAAAAC/9xabW8buRH+KwTbDzl0YyVO0vT06fLiS4ymsSErBQ6BUXB3RxLPu+SW5EpVAv/3Ykju",
+	"OyVZ8gvifBNWw+FwnofDmSG/00TmhRQgjKbj71TBf0vQ5q1MOdgPcZkX5xrKVIp1/m9QmksxcUL4dyKF",
+	"AWF/sqLIeMIMl2L0p5YCv+lkATnDX4WSBSjjtbIy5SASwN8p6ETxAsfRMZVqzgTXVg2RM7Ja8GRBzAJI",
+	"UVmhCVNA0DBIaUTNugA6ptooLub0OqI8BWH4jINC9X9VMKNj+pdRs9CRM0uPWpLXUWfq3YahSSzNueDa",
+	"KGakishqAYLkpSlZRqafLgjXpNSQEm5IXmpDcmb8WkLKkoyDMCRBL83QkRBanE5kAbvW5YSuryOLJ1eQ",
+	"0vHXtl+iBoHLehIZ/wmJodfdcUaVcB1R+F+yYGIOp7WS27Pg4YBKWJbBAyGkIOEFCjaumq4LuPkSUVrv",
+	"i3VEtSnT9U5pK7SVGZvsr+zZkzBTeQXiLrlyoDN/bNLcH9QRNYjALmkn1CeG+xr1vb83FYqMcXEuM56s",
+	"H+zwcCeHggT4ErTFQoEuM1MhUzE0aqHtwGaxRrsCODmpR88fWaoEqo3UXUkDNcFZvBu5rt2VRkQqMj37",
+	"58lnMpOKMGJZUv8fMs8wNQez54Q+F8EJh7P5P9GUs/PJ7/85vzj58v7MiQj7icCSZaXb7wOTejxvOaRj",
+	"7J5Ex2F3He72Oxo93e2o25IERPpDUp2LeQZfdIBKqMTRI2GCSJGtSdzs85RIkUDkJFwSybIVW2viVD4t",
+	"NbgVoB4NaskTIB5kTXgrIMRSZsDEzoPUYbEni2ShZieOu3B7JsUZFymkJxnkfnTXZzHT8PeXBEQiU0iJ",
+	"4tooMEYev3pFwA2q8PGqSGvHPpn8/o78+vLX11Fn5NOLj29ePT/+hUZ0JlXODE60DuN5BevTdGjX6ftq",
+	"2jrxJ1ewJkZWGxvIipuFi94sMXwJVqAVwY/ItDNezjA4tMx3xNBWkdVzBevI81B356n+JrVhXBFgKkM9",
+	"rdrESDLjIkWBnLCZAYxZOE5JsyEY/UzJSW9D9Ni3505QsJRXcKp1eSflhtVzo6zBiloMfbSIAg5lpVmA",
+	"MNZtKdkM0s7Txxu2l1Pu6JzZTjxnGJ6xVc5Vrcx65WHYd+v09UZ+tV90IYX2YZOlu71bKBlnkP9t6OVt",
+	"tvpRzozhAVZlNlwTIQ1Zsoyn6IdwJ8aZvBcDttm2dZINBi+dGFkwTWIAUbVmNnQNdlqcxFLd3OItU6C5",
+	"h7tiq+KBI3SZJKD1rMwI7i87IR2Wwfe0+K72u1l3X+ceS+6Ue3fM0LD2gHkpJFy3ok1hR6CBM6linqYg",
+	"thh1L1u7EwT9/mZZJld41khSgMJsqVecwtH8CAOql99+8tBOFXLHbBsovh3RAuoGXvsApi75Grl+jnzH",
+	"FAsq32MDlAIBkop/g/ShKebbGBW5ekRpzu7B8VKKQklcDoszeGir2yk5E9awGIg3CNLOJshZhrvEracl",
+	"nUKi1oXBg8fmrHbizVcYDWe6GZE/zYbJkIBVfdT1KxMdtX67cgEL0CwFVY/BelMBJiD22qKui7gwL46b",
+	"xIcLA3NQg1ymsmuYzew6Zu+is3C9ZdZBsLm/CTccLN0JfUQdIrhagFmA6sRXZFQBKufGQBoo8BEE5qk+",
+	"bPWXWaALIVhe58ko4WsKPI9S5LKrTZtOQwoz1mpLunOKrJgmdt+FLrl63KhWXBsbIknoWOg6bs9MezBD",
+	"F+me7sO69hiFIeD7/uawYt6ky62mOdXj7xREmePYtxefaUTPJh/efD69eDM9Pfvsm4k0oudnn/7419nk",
+	"/OPpu+bjyed3kz/Opyfvm0+Tk4uTN5N3H6svl6Eaf8OR1fVT1XO4VcMGe1wYEmdcsAxPIRKvWxXXfXRl",
+	"FlBNbplbL8MGwyCBBxhVZ8RgUv8HScEwnmnfxgGlsDDVxAnHWHYKgv2o1/949ppGPce6wUPlNqiIVl3K",
+	"NZFJUirVrncr2wKe4kIbFryO+DI5JQpm4DT5zkJFRH3AVNowU+rhRB+n03Pi/iTIijr6VDQbni4RNdyE",
+	"opdeSGWILvOcqXXPJtugDxlm1sUh69+lOMSSuhnV40ipCqmB9LKJqiExw/v5b1LNyROjgBkk6i+YQEiR",
+	"gvom4Yo8UaCBqWSBXcsqOOAQGtFaKriz6+u4jRcbDR52BmJHRFVv2ZrX/NlkEjSiORefQMzNgo6fb2uO",
+	"9D1niTmTQ6suAMhXnw5x3LAXrtF9+WRhTKHHo9Gcm0UZHyUyH+VcLFeYvemnqUxK9JrdKb/Qmj+0rYt4",
+	"ZeTJb3NuUEXOzRFPj1gcK1j+huPqBIs+O3p+9MyFRhCs4HRMX9hPES2YWViij+wbi2BfAv8upGvQ1Pk3",
+	"Bi36NiQdtR63bLw87bx/GW17/NJvGB0/e7ZZqZfb0V6J6MsbKWlaU3bI891DOhWJHfRi96CmTMYRx8c3",
+	"maZdQ9g03MURPGjKvCDtdtEgh8YvjOjSbvXqJq9qPEZ1L70e0DSc0Bglc65drsTmGvfuG2QOvUQzAs2c",
+	"zew5GcoewJ3ND2YOYs62btRPzZs6Ue/e3Dh6SJvPd27dKvRb6HUpMK1C5nb0p/7pw8HAdzr0t8K81yX5",
+	"ceEOA+f6DX4/B7GqfD3ArKpZNsP1oZI4AKn+df1BIA27WD/1dpw3DbkBgA4zrHNGVf6/GbizVjl0CHih",
+	"W/KDAAx3+x7LJvOGExa6na/vre17GKzTXOnU6Rg3KCIkHkTXhKia7duiZaslc1i0DLwNOzBaBi8GHk20",
+	"tNaTdo/Kd4Jce0ojcM2tAEIrS1PdGXAx9w9UPJYeEYdm+x59M5aTttQBUIZu64dIvtzybEdXSaG/eF6w",
+	"JbgMz+lOHw2czl683KlW5p8UxOv+BiSlMDwjQq6i4U25vUWPezc+GwJv62HALpAPPjIDjw/2g7hJ2h8r",
+	"pNXbB27atw/NIzMm1rlUEAIJ9YHCAoiOv36npcromI6Wz+n15fX/BwC4LFKMijEAAA==",
+}
+
+// GetSwagger returns the content of the embedded swagger specification file
+// or error if failed to decode
+func decodeSpec() ([]byte, error) {
+	zipped, err := base64.StdEncoding.DecodeString(strings.Join(swaggerSpec, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error base64 decoding spec: %w", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(zipped))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(zr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing spec: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+var rawSpec = decodeSpecCached()
+
+// a naive cached of a decoded swagger spec
+func decodeSpecCached() func() ([]byte, error) {
+	data, err := decodeSpec()
+	return func() ([]byte, error) {
+		return data, err
+	}
+}
+
+// Constructs a synthetic filesystem for resolving external references when loading openapi specifications.
+func PathToRawSpec(pathToFile string) map[string]func() ([]byte, error) {
+	res := make(map[string]func() ([]byte, error))
+	if len(pathToFile) > 0 {
+		res[pathToFile] = rawSpec
+	}
+
+	return res
+}
+
+// GetSwagger returns the Swagger specification corresponding to the generated code
+// in this file. The external references of Swagger specification are resolved.
+// The logic of resolving external references is tightly connected to "import-mapping" feature.
+// Externally referenced files must be embedded in the corresponding golang packages.
+// Urls can be supported but this task was out of the scope.
+func GetSwagger() (swagger *openapi3.T, err error) {
+	resolvePath := PathToRawSpec("")
+
+	loader := openapi3.NewLoader()
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = func(loader *openapi3.Loader, url *url.URL) ([]byte, error) {
+		pathToFile := url.String()
+		pathToFile = path.Clean(pathToFile)
+		getSpec, ok := resolvePath[pathToFile]
+		if !ok {
+			err1 := fmt.Errorf("path not found: %s", pathToFile)
+			return nil, err1
+		}
+		return getSpec()
+	}
+	var specData []byte
+	specData, err = rawSpec()
+	if err != nil {
+		return
+	}
+	swagger, err = loader.LoadFromData(specData)
+	if err != nil {
+		return
+	}
+	return
+}
@@ -2,78 +2,511 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/stevenvegt/pseudonyms/audit"
+	"github.com/stevenvegt/pseudonyms/auth"
 	domain "github.com/stevenvegt/pseudonyms/domain"
+	"github.com/stevenvegt/pseudonyms/keys"
+	"github.com/stevenvegt/pseudonyms/policy"
 	pb "github.com/stevenvegt/pseudonyms/proto"
+	"github.com/stevenvegt/pseudonyms/registry"
+	"github.com/stevenvegt/pseudonyms/replay"
+	"github.com/stevenvegt/pseudonyms/revocation"
+	"github.com/stevenvegt/pseudonyms/versions"
 )
 
-// TODO: Should be passed in from main and not in the API section
-// Example key (must be 16, 24, or 32 bytes for AES-128, AES-192, AES-256)
-var key = []byte("examplekey1234567890123456789012")
-
 var _ StrictServerInterface = (*PseudonymService)(nil)
 
 type PseudonymService struct {
+	keyProvider      keys.KeyProvider
+	revokedAudiences map[string]bool
+	clock            func() time.Time
+	leeway           time.Duration
+	scopes           map[Scope]pb.Scope
+	requireIdentity  bool
+	registry         *registry.Registry
+	policy           *policy.Policy
+	auditLog         audit.Log
+	replayCache      replay.Cache
+	revocations      revocation.Store
+	versions         versions.Store
+	tokenFormat      domain.TokenFormat
+	singleUseTokens  bool
+}
+
+// Option configures optional behaviour of the PseudonymService.
+type Option func(*PseudonymService)
+
+// WithRevokedAudiences revokes the pseudonym keys of the given audiences.
+// No pseudonyms are created or decrypted for these audiences, other audiences are not affected.
+func WithRevokedAudiences(audiences ...string) Option {
+	return func(ps *PseudonymService) {
+		for _, audience := range audiences {
+			ps.revokedAudiences[audience] = true
+		}
+	}
+}
+
+// WithClock sets the clock used to issue and validate tokens, e.g. a fixed time in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(ps *PseudonymService) {
+		ps.clock = clock
+	}
+}
+
+// WithLeeway sets the allowed clock skew when validating the lifetime of tokens.
+func WithLeeway(leeway time.Duration) Option {
+	return func(ps *PseudonymService) {
+		ps.leeway = leeway
+	}
+}
+
+// WithScopeMapping sets which scope of tokens and pseudonyms is used for each scope of the API.
+// Scopes of the API which are not in the mapping are rejected.
+func WithScopeMapping(mapping map[Scope]pb.Scope) Option {
+	return func(ps *PseudonymService) {
+		ps.scopes = mapping
+	}
+}
+
+// WithRequiredIdentity requires an authenticated identity of the caller for every request, e.g. from its client certificate.
+func WithRequiredIdentity() Option {
+	return func(ps *PseudonymService) {
+		ps.requireIdentity = true
+	}
+}
+
+// WithRegistry only allows the organisations in the registry to use the service, for the scopes and identifier types
+// they are allowed to use.
+func WithRegistry(registry *registry.Registry) Option {
+	return func(ps *PseudonymService) {
+		ps.registry = registry
+	}
+}
+
+// WithPolicy only permits the exchanges which are allowed by the policy.
+func WithPolicy(policy *policy.Policy) Option {
+	return func(ps *PseudonymService) {
+		ps.policy = policy
+	}
+}
+
+// WithAuditLog records every exchange in the audit log.
+func WithAuditLog(auditLog audit.Log) Option {
+	return func(ps *PseudonymService) {
+		ps.auditLog = auditLog
+	}
+}
+
+// WithReplayCache sets the cache which remembers the single-use tokens which have been exchanged.
+func WithReplayCache(cache replay.Cache) Option {
+	return func(ps *PseudonymService) {
+		ps.replayCache = cache
+	}
+}
+
+// WithSingleUseTokens makes all tokens single-use, otherwise only the tokens requested as single-use are.
+func WithSingleUseTokens() Option {
+	return func(ps *PseudonymService) {
+		ps.singleUseTokens = true
+	}
+}
+
+// WithRevocationStore sets the store which keeps the revoked tokens and issuers.
+func WithRevocationStore(store revocation.Store) Option {
+	return func(ps *PseudonymService) {
+		ps.revocations = store
+	}
+}
+
+// WithVersionStore sets the store which keeps the versions of the pseudonyms.
+func WithVersionStore(store versions.Store) Option {
+	return func(ps *PseudonymService) {
+		ps.versions = store
+	}
+}
+
+// WithTokenFormat sets the format of the tokens, for the organisations without a token format in the registry.
+func WithTokenFormat(format domain.TokenFormat) Option {
+	return func(ps *PseudonymService) {
+		ps.tokenFormat = format
+	}
+}
+
+// DefaultScopeMapping maps zorg to the treatment scope and onderzoek to the research scope.
+func DefaultScopeMapping() map[Scope]pb.Scope {
+	return map[Scope]pb.Scope{
+		Zorg:      pb.Scope_TREATMENT,
+		Onderzoek: pb.Scope_RESEARCH,
+	}
 }
 
-func NewPseudonymService() *PseudonymService {
-	return &PseudonymService{}
+// ParseScopeMapping parses a scope mapping in the form "zorg=TREATMENT,onderzoek=RESEARCH".
+func ParseScopeMapping(value string) (map[Scope]pb.Scope, error) {
+	mapping := map[Scope]pb.Scope{}
+	for _, entry := range strings.Split(value, ",") {
+		apiScope, pbScope, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid scope mapping: %s", entry)
+		}
+		scope, ok := pb.Scope_value[pbScope]
+		if !ok {
+			return nil, fmt.Errorf("unknown scope: %s", pbScope)
+		}
+		mapping[Scope(apiScope)] = pb.Scope(scope)
+	}
+	return mapping, nil
+}
+
+func NewPseudonymService(keyProvider keys.KeyProvider, opts ...Option) *PseudonymService {
+	ps := &PseudonymService{
+		keyProvider:      keyProvider,
+		revokedAudiences: map[string]bool{},
+		clock:            time.Now,
+		leeway:           30 * time.Second,
+		scopes:           DefaultScopeMapping(),
+		replayCache:      replay.NewMemoryCache(100_000),
+		revocations:      revocation.NewMemoryStore(),
+		versions:         versions.NewMemoryStore(),
+		tokenFormat:      domain.TokenFormatContainer,
+	}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	return ps
+}
+
+// parseScope returns the scope of tokens and pseudonyms for a scope of the API.
+func (ps *PseudonymService) parseScope(scope Scope) (pb.Scope, error) {
+	pbScope, ok := ps.scopes[scope]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown scope: %s", ErrInvalidRequest, scope)
+	}
+	return pbScope, nil
+}
+
+// callerOrganisation returns the organisation of the caller. When the caller is authenticated its organisation is used,
+// an organisation in the request must be the same. Otherwise the organisation in the request is used, unless an
+// authenticated identity is required.
+func (ps *PseudonymService) callerOrganisation(ctx context.Context, organisation *string) (string, error) {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		if ps.requireIdentity {
+			return "", auth.ErrUnauthenticated
+		}
+		if organisation == nil {
+			return "", fmt.Errorf("%w: organisation is required", ErrInvalidRequest)
+		}
+		return *organisation, nil
+	}
+
+	if organisation != nil && *organisation != identity.Organisation {
+		return "", fmt.Errorf("%w: %s is not %s", ErrOrganisationMismatch, *organisation, identity.Organisation)
+	}
+	return identity.Organisation, nil
+}
+
+// checkGrantedScope checks that the scope is granted to the caller, e.g. in the scope claim of its access token.
+// Callers which are not authenticated, or not restricted to scopes, may use every scope.
+func (ps *PseudonymService) checkGrantedScope(ctx context.Context, scope Scope) error {
+	if identity, ok := auth.IdentityFromContext(ctx); ok && !identity.HasScope(string(scope)) {
+		return fmt.Errorf("%w: %s", ErrScopeNotGranted, scope)
+	}
+	return nil
+}
+
+// authorise checks that the organisation may use the scope and receive identifiers of the type, when an identifier
+// type is given. Without a registry every organisation is allowed.
+func (ps *PseudonymService) authorise(organisation string, scope Scope, identifierType IdentifierTypes) error {
+	if ps.registry == nil {
+		return nil
+	}
+	return ps.registry.Authorise(organisation, string(scope), string(identifierType))
+}
+
+// authoriseAdmin checks that the organisation may use the administrative endpoints.
+// Only the administrators in the registry are allowed, without a registry no organisation is.
+func (ps *PseudonymService) authoriseAdmin(organisation string) error {
+	if ps.registry == nil {
+		return fmt.Errorf("%w: no registry with administrators is configured", ErrAdminNotPermitted)
+	}
+	if err := ps.registry.AuthoriseAdmin(organisation); err != nil {
+		return fmt.Errorf("%w: %w", ErrAdminNotPermitted, err)
+	}
+	return nil
+}
+
+// createToken encrypts the token in the token format of its audience, as a JWE, a CWT, a PASETO or as a container in
+// the format of the audience. Without a token format in the registry the token format of the service is used.
+func (ps *PseudonymService) createToken(token *pb.Token) (string, error) {
+	keyring, err := ps.keyProvider.TokenKeys()
+	if err != nil {
+		return "", err
+	}
+
+	tokenFormat := ps.tokenFormat
+	if ps.registry != nil {
+		receiver, err := ps.registry.Organisation(token.Audience)
+		if err != nil {
+			return "", err
+		}
+		if receiver.TokenFormat != "" {
+			tokenFormat = receiver.TokenFormat
+		}
+	}
+
+	switch tokenFormat {
+	case domain.TokenFormatJWE:
+		return domain.CreateTokenJWE(token, keyring)
+	case domain.TokenFormatCWT:
+		return domain.CreateTokenCWT(token, keyring)
+	case domain.TokenFormatPASETO:
+		return domain.CreateTokenPASETO(token, keyring)
+	}
+	tokenString, err := domain.CreateToken(token, keyring)
+	if err != nil {
+		return "", err
+	}
+	return ps.formatIdentifier(tokenString, token.Audience)
+}
+
+// formatIdentifier returns a token or pseudonym in the format of the organisation which receives it.
+// Without a registry the compact format is used.
+func (ps *PseudonymService) formatIdentifier(value string, organisation string) (string, error) {
+	if ps.registry == nil {
+		return value, nil
+	}
+	receiver, err := ps.registry.Organisation(organisation)
+	if err != nil {
+		return "", err
+	}
+	return domain.FormatIdentifier(value, receiver.Format)
+}
+
+// evaluatePolicy decides whether the policy permits the exchange, without a policy every exchange is permitted.
+func (ps *PseudonymService) evaluatePolicy(request policy.Request) policy.Decision {
+	if ps.policy == nil {
+		return policy.Decision{Allowed: true, Reason: "no policy is configured"}
+	}
+	return ps.policy.Evaluate(request)
+}
+
+// checkPolicy returns an error when the policy does not permit the exchange.
+func (ps *PseudonymService) checkPolicy(request policy.Request) error {
+	if decision := ps.evaluatePolicy(request); !decision.Allowed {
+		return fmt.Errorf("%w: %s", policy.ErrDenied, decision.Reason)
+	}
+	return nil
+}
+
+// checkRevocation returns an error when the token, or all tokens of its issuer, have been revoked.
+func (ps *PseudonymService) checkRevocation(token *pb.Token) error {
+	err := ps.revocations.Check(token.Jti, token.Issuer, time.Unix(token.IssuedAt, 0))
+	if errors.Is(err, revocation.ErrTokenRevoked) || errors.Is(err, revocation.ErrIssuerRevoked) {
+		return fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return err
+}
+
+// recordAudit records the exchange in the audit log. When the record can not be written the exchange fails,
+// so there is no exchange without a record.
+func (ps *PseudonymService) recordAudit(event audit.Event) error {
+	if ps.auditLog == nil {
+		return nil
+	}
+	if err := ps.auditLog.Record(event); err != nil {
+		log.Printf("failed to record %s in audit log: %v", event.Operation, err)
+		return fmt.Errorf("failed to record exchange in audit log")
+	}
+	return nil
+}
+
+// createPseudonym creates the pseudonym of the subject for the audience with its current version, encrypted with
+// the key of the audience. The pseudonym is a COSE message when it is the pseudonym format of the audience in the
+// registry, and a container otherwise.
+func (ps *PseudonymService) createPseudonym(subject string, audience string, scope pb.Scope) (string, error) {
+	if ps.revokedAudiences[audience] {
+		return "", fmt.Errorf("%w: %s", ErrAudienceRevoked, audience)
+	}
+
+	version, err := ps.versions.Current(subject, audience)
+	if err != nil {
+		return "", err
+	}
+
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return "", err
+	}
+
+	pseudonym := &pb.Pseudonym{
+		Subject:  subject,
+		Audience: audience,
+		Scope:    scope,
+		Version:  version,
+	}
+	if ps.registry != nil {
+		receiver, err := ps.registry.Organisation(audience)
+		if err != nil {
+			return "", err
+		}
+		if receiver.PseudonymFormat == domain.PseudonymFormatCOSE {
+			return domain.CreatePseudonymCOSE(pseudonym, keyring)
+		}
+	}
+	return domain.CreatePseudonym(pseudonym, keyring)
+}
+
+// decryptPseudonym decrypts a pseudonym which belongs to the given audience and scope.
+func (ps *PseudonymService) decryptPseudonym(pseudonymString string, audience string, scope pb.Scope) (*pb.Pseudonym, error) {
+	if ps.revokedAudiences[audience] {
+		return nil, fmt.Errorf("%w: %s", ErrAudienceRevoked, audience)
+	}
+
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonym, err := domain.DecryptPseudonum(pseudonymString, audience, scope, keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	// a pseudonym is superseded when its version has been bumped, e.g. because it has been compromised
+	version, err := ps.versions.Current(pseudonym.Subject, pseudonym.Audience)
+	if err != nil {
+		return nil, err
+	}
+	if pseudonym.Version < version {
+		return nil, fmt.Errorf("%w: version %d, current version is %d", ErrPseudonymSuperseded, pseudonym.Version, version)
+	}
+	return pseudonym, nil
+}
+
+// createResearchPseudonym creates an irreversible pseudonym of the subject for a study of the audience.
+func (ps *PseudonymService) createResearchPseudonym(subject string, audience string, study string) (string, error) {
+	if ps.revokedAudiences[audience] {
+		return "", fmt.Errorf("%w: %s", ErrAudienceRevoked, audience)
+	}
+
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return "", err
+	}
+
+	return domain.CreateResearchPseudonym(subject, audience, study, keyring)
+}
+
+// createPolymorphicPseudonym creates a polymorphic pseudonym of the subject.
+func (ps *PseudonymService) createPolymorphicPseudonym(subject string) (string, error) {
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return "", err
+	}
+
+	return domain.CreatePolymorphicPseudonym(subject, keyring)
+}
+
+// transformPolymorphicPseudonym transforms a polymorphic pseudonym into an encrypted pseudonym for the audience.
+func (ps *PseudonymService) transformPolymorphicPseudonym(polymorphicPseudonym string, audience string, scope pb.Scope) (string, error) {
+	if ps.revokedAudiences[audience] {
+		return "", fmt.Errorf("%w: %s", ErrAudienceRevoked, audience)
+	}
+
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return "", err
+	}
+
+	return domain.TransformPolymorphicPseudonym(polymorphicPseudonym, audience, scope, keyring)
 }
 
 // ExchangeIdentifier exchanges an identifier for a pseudonym or vice versa.
 // So, As an organisation, if you have a BSN, you can get your own pseudonym. Or, if you have a pseudonym, you can get the BSN of the subject.
-func (ps *PseudonymService) ExchangeIdentifier(ctx context.Context, exchangeIdentifierRequest ExchangeIdentifierRequestObject) (ExchangeIdentifierResponseObject, error) {
+func (ps *PseudonymService) ExchangeIdentifier(ctx context.Context, exchangeIdentifierRequest ExchangeIdentifierRequestObject) (response ExchangeIdentifierResponseObject, err error) {
+	event := audit.Event{Operation: "exchangeIdentifier", Scope: string(exchangeIdentifierRequest.Body.Scope)}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
 
 	var (
-		idValue  string
-		idType   IdentifierTypes
-		subject  string
-		audience string
+		idValue              string
+		idType               IdentifierTypes
+		subject              string
+		audience             string
+		polymorphicPseudonym string
 	)
 
-	if exchangeIdentifierRequest.Body.Identifier == nil {
-		return nil, fmt.Errorf("identifier is required")
-	}
+	sourceIdentifierType := exchangeIdentifierRequest.Body.Identifier.Type
+	targetIdentifierType := exchangeIdentifierRequest.Body.RecipientIdentifierType
 
-	if exchangeIdentifierRequest.Body.Identifier.Value == nil || exchangeIdentifierRequest.Body.Identifier.Type == nil {
-		return nil, fmt.Errorf("identifier value and type are required")
+	if sourceIdentifierType == targetIdentifierType {
+		return nil, fmt.Errorf("%w: source and target identifier types cannot be the same", ErrInvalidRequest)
 	}
 
-	if exchangeIdentifierRequest.Body.RecipientIdentifierType == nil {
-		return nil, fmt.Errorf("recipient identifier type is required")
+	scope, err := ps.parseScope(exchangeIdentifierRequest.Body.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.checkGrantedScope(ctx, exchangeIdentifierRequest.Body.Scope); err != nil {
+		return nil, err
 	}
 
-	sourceIdentifierType := *exchangeIdentifierRequest.Body.Identifier.Type
-	targetIdentifierType := *exchangeIdentifierRequest.Body.RecipientIdentifierType
+	// polymorphic pseudonyms are not bound to an organisation, but with a registry only known organisations can create them
+	if sourceIdentifierType != BSN || targetIdentifierType != POLYMORPHICPSEUDO || ps.registry != nil {
+		audience, err = ps.callerOrganisation(ctx, exchangeIdentifierRequest.Body.Organisation)
+		if err != nil {
+			return nil, err
+		}
+		event.Caller = audience
+		event.Audience = audience
+		if err := ps.authorise(audience, exchangeIdentifierRequest.Body.Scope, targetIdentifierType); err != nil {
+			return nil, err
+		}
+	}
 
-	if sourceIdentifierType == targetIdentifierType {
-		return nil, fmt.Errorf("source and target identifier types cannot be the same")
+	if err := ps.checkPolicy(policy.Request{
+		Source:   string(sourceIdentifierType),
+		Target:   string(targetIdentifierType),
+		Scope:    string(exchangeIdentifierRequest.Body.Scope),
+		Caller:   audience,
+		Audience: audience,
+	}); err != nil {
+		return nil, err
 	}
 
 	switch sourceIdentifierType {
 	case BSN:
-		if exchangeIdentifierRequest.Body.Organisation == nil {
-			return nil, fmt.Errorf("organisation is required for BSN to pseudonym exchange")
-		}
-		audience = *exchangeIdentifierRequest.Body.Organisation
-		subject = *exchangeIdentifierRequest.Body.Identifier.Value
+		subject = exchangeIdentifierRequest.Body.Identifier.Value
+		event.Subject = subject
 	case ORGANISATIONPSEUDO:
-		pseudonymString := *exchangeIdentifierRequest.Body.Identifier.Value
-		pseudonym, err := domain.DecryptPseudonum(pseudonymString, key)
+		pseudonymString := exchangeIdentifierRequest.Body.Identifier.Value
+		pseudonym, err := ps.decryptPseudonym(pseudonymString, audience, scope)
 		if err != nil {
 			return nil, err
 		}
 		subject = pseudonym.Subject
 		audience = pseudonym.Audience
-		if exchangeIdentifierRequest.Body.Organisation != nil && *exchangeIdentifierRequest.Body.Organisation != pseudonym.Audience {
-			return nil, fmt.Errorf("organisation does not match pseudonym audience")
+		event.Subject = subject
+	case POLYMORPHICPSEUDO:
+		// polymorphic pseudonyms are never decrypted, they can only be transformed into encrypted pseudonyms
+		if targetIdentifierType != ENCRYPTEDPSEUDO {
+			return nil, fmt.Errorf("%w: polymorphic pseudonyms can only be exchanged for encrypted pseudonyms", ErrInvalidRequest)
 		}
-		audience = pseudonym.Audience
+		polymorphicPseudonym = exchangeIdentifierRequest.Body.Identifier.Value
+	case RESEARCHPSEUDO:
+		return nil, domain.ErrIrreversiblePseudonym
 	default:
-		return nil, fmt.Errorf("unsupported identifier type: %s", *exchangeIdentifierRequest.Body.Identifier.Type)
+		return nil, fmt.Errorf("%w: unsupported identifier type: %s", ErrInvalidRequest, exchangeIdentifierRequest.Body.Identifier.Type)
 	}
 
 	switch targetIdentifierType {
@@ -81,101 +514,495 @@ func (ps *PseudonymService) ExchangeIdentifier(ctx context.Context, exchangeIden
 		idValue = subject
 		idType = BSN
 	case ORGANISATIONPSEUDO:
-		pseudonym := &pb.Pseudonym{
-			Subject:  subject,
-			Audience: audience,
-			Scope:    pb.Scope_TREATMENT,
-			Version:  1,
-		}
-
-		pseudonymString, err := domain.CreatePseudonym(pseudonym, key)
+		pseudonymString, err := ps.createPseudonym(subject, audience, scope)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		idValue = pseudonymString
 		idType = ORGANISATIONPSEUDO
+	case POLYMORPHICPSEUDO:
+		pseudonymString, err := ps.createPolymorphicPseudonym(subject)
+		if err != nil {
+			return nil, err
+		}
+		idValue = pseudonymString
+		idType = POLYMORPHICPSEUDO
+	case ENCRYPTEDPSEUDO:
+		if polymorphicPseudonym == "" {
+			pseudonymString, err := ps.createPolymorphicPseudonym(subject)
+			if err != nil {
+				return nil, err
+			}
+			polymorphicPseudonym = pseudonymString
+		}
+		pseudonymString, err := ps.transformPolymorphicPseudonym(polymorphicPseudonym, audience, scope)
+		if err != nil {
+			return nil, err
+		}
+		idValue = pseudonymString
+		idType = ENCRYPTEDPSEUDO
+	case RESEARCHPSEUDO:
+		if scope != pb.Scope_RESEARCH {
+			return nil, fmt.Errorf("%w: research pseudonyms can only be requested for the research scope", ErrInvalidRequest)
+		}
+		if exchangeIdentifierRequest.Body.Study == nil {
+			return nil, fmt.Errorf("%w: study is required for research pseudonyms", ErrInvalidRequest)
+		}
+		pseudonymString, err := ps.createResearchPseudonym(subject, audience, *exchangeIdentifierRequest.Body.Study)
+		if err != nil {
+			return nil, err
+		}
+		idValue = pseudonymString
+		idType = RESEARCHPSEUDO
+	default:
+		return nil, fmt.Errorf("%w: unsupported recipient identifier type: %s", ErrInvalidRequest, targetIdentifierType)
+	}
+
+	if idType != BSN {
+		idValue, err = ps.formatIdentifier(idValue, audience)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return ExchangeIdentifier200JSONResponse{
 		ExchangeIdentifierResponseJSONResponse{
-			Identifier: &Identifier{Value: &idValue, Type: &idType},
+			Identifier: &Identifier{Value: idValue, Type: idType},
 		},
 	}, nil
 }
 
-func (ps *PseudonymService) ExchangeToken(ctx context.Context, exchangeTokenRequest ExchangeTokenRequestObject) (ExchangeTokenResponseObject, error) {
+func (ps *PseudonymService) ExchangeToken(ctx context.Context, exchangeTokenRequest ExchangeTokenRequestObject) (response ExchangeTokenResponseObject, err error) {
+	event := audit.Event{Operation: "exchangeToken", Scope: string(exchangeTokenRequest.Body.Scope)}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
 
 	var (
 		idValue string
 		idType  IdentifierTypes
 	)
 
-	tokenString := *exchangeTokenRequest.Body.Token
-	decryptedToken, err := domain.DecryptToken(tokenString, key)
+	organisation, err := ps.callerOrganisation(ctx, exchangeTokenRequest.Body.Organisation)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = organisation
+	if err := ps.checkGrantedScope(ctx, exchangeTokenRequest.Body.Scope); err != nil {
+		return nil, err
+	}
+
+	keyring, err := ps.keyProvider.TokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenString := exchangeTokenRequest.Body.Token
+	decryptedToken, err := domain.DecryptToken(tokenString, keyring)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	event.Audience = decryptedToken.Audience
+	event.Subject = decryptedToken.Subject
+
+	if err := domain.ValidateTokenLifetime(decryptedToken, ps.clock(), ps.leeway); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if err := ps.checkRevocation(decryptedToken); err != nil {
+		return nil, err
 	}
 
-	switch *exchangeTokenRequest.Body.IdentifierType {
+	// only the organisation the token is issued for can exchange it, and only for one of its scopes
+	if err := domain.ValidateTokenAudience(decryptedToken, organisation); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	scope, err := ps.parseScope(exchangeTokenRequest.Body.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.authorise(organisation, exchangeTokenRequest.Body.Scope, exchangeTokenRequest.Body.IdentifierType); err != nil {
+		return nil, err
+	}
+	if err := ps.checkPolicy(policy.Request{
+		Source:   policy.Token,
+		Target:   string(exchangeTokenRequest.Body.IdentifierType),
+		Scope:    string(exchangeTokenRequest.Body.Scope),
+		Caller:   organisation,
+		Audience: organisation,
+	}); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateTokenScope(decryptedToken, scope); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	if decryptedToken.SingleUse && decryptedToken.Jti == "" {
+		return nil, fmt.Errorf("%w: single-use token has no id", ErrInvalidToken)
+	}
+
+	switch exchangeTokenRequest.Body.IdentifierType {
 	case BSN:
 		idValue = decryptedToken.Subject
 		idType = BSN
 	case ORGANISATIONPSEUDO:
-		pseudonym := &pb.Pseudonym{
-			Subject:  decryptedToken.Subject,
-			Audience: decryptedToken.Audience,
-			Scope:    decryptedToken.Scopes[0],
-			Version:  1,
-		}
-
-		pseudonymString, err := domain.CreatePseudonym(pseudonym, key)
+		pseudonymString, err := ps.createPseudonym(decryptedToken.Subject, decryptedToken.Audience, scope)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 		idValue = pseudonymString
 		idType = ORGANISATIONPSEUDO
+	case RESEARCHPSEUDO:
+		if scope != pb.Scope_RESEARCH {
+			return nil, fmt.Errorf("%w: research pseudonyms can only be requested for the research scope", ErrInvalidRequest)
+		}
+		if exchangeTokenRequest.Body.Study == nil {
+			return nil, fmt.Errorf("%w: study is required for research pseudonyms", ErrInvalidRequest)
+		}
+		pseudonymString, err := ps.createResearchPseudonym(decryptedToken.Subject, decryptedToken.Audience, *exchangeTokenRequest.Body.Study)
+		if err != nil {
+			return nil, err
+		}
+		idValue = pseudonymString
+		idType = RESEARCHPSEUDO
+	default:
+		return nil, fmt.Errorf("%w: unsupported identifier type: %s", ErrInvalidRequest, exchangeTokenRequest.Body.IdentifierType)
+	}
+
+	if idType != BSN {
+		idValue, err = ps.formatIdentifier(idValue, decryptedToken.Audience)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// single-use tokens are remembered until they expire, including the leeway of the lifetime. They are only marked
+	// as used when the exchange succeeds, so a request which fails does not use up the token.
+	if decryptedToken.SingleUse {
+		expiration := time.Unix(decryptedToken.Expiration, 0).Add(ps.leeway)
+		if err := ps.replayCache.Use(decryptedToken.Jti, expiration); err != nil {
+			if errors.Is(err, replay.ErrReplayed) {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+			}
+			return nil, err
+		}
 	}
 
 	return ExchangeToken200JSONResponse{ExchangeTokenResponseJSONResponse{Identifier: &Identifier{
-		Value: &idValue,
-		Type:  &idType,
+		Value: idValue,
+		Type:  idType,
 	}}}, nil
 }
 
-func (ps *PseudonymService) GetToken(ctx context.Context, getTokenRequest GetTokenRequestObject) (GetTokenResponseObject, error) {
+func (ps *PseudonymService) GetToken(ctx context.Context, getTokenRequest GetTokenRequestObject) (response GetTokenResponseObject, err error) {
+	event := audit.Event{Operation: "getToken", Scope: string(getTokenRequest.Body.Scope)}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
 
 	var (
 		subject string
 	)
 
-	switch *getTokenRequest.Body.Identifier.Type {
+	sender, err := ps.callerOrganisation(ctx, getTokenRequest.Body.Sender)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = sender
+	event.Audience = getTokenRequest.Body.Receiver
+
+	scope, err := ps.parseScope(getTokenRequest.Body.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// the caller can only issue tokens for the scopes granted to it
+	if err := ps.checkGrantedScope(ctx, getTokenRequest.Body.Scope); err != nil {
+		return nil, err
+	}
+
+	// both the sender and the receiver of the token must be allowed to use the scope
+	if err := ps.authorise(sender, getTokenRequest.Body.Scope, ""); err != nil {
+		return nil, err
+	}
+	if err := ps.authorise(getTokenRequest.Body.Receiver, getTokenRequest.Body.Scope, ""); err != nil {
+		return nil, err
+	}
+	if err := ps.checkPolicy(policy.Request{
+		Source:   string(getTokenRequest.Body.Identifier.Type),
+		Target:   policy.Token,
+		Scope:    string(getTokenRequest.Body.Scope),
+		Caller:   sender,
+		Audience: getTokenRequest.Body.Receiver,
+	}); err != nil {
+		return nil, err
+	}
+
+	switch getTokenRequest.Body.Identifier.Type {
 	case BSN:
-		subject = *getTokenRequest.Body.Identifier.Value
+		subject = getTokenRequest.Body.Identifier.Value
+		event.Subject = subject
 	case ORGANISATIONPSEUDO:
-		pseudonymString := *getTokenRequest.Body.Identifier.Value
-		decryptedPseudonym, err := domain.DecryptPseudonum(pseudonymString, key)
+		// the sender requests a token for a pseudonym of its own
+		pseudonymString := getTokenRequest.Body.Identifier.Value
+		decryptedPseudonym, err := ps.decryptPseudonym(pseudonymString, sender, scope)
 		if err != nil {
 			return nil, err
 		}
 
 		subject = decryptedPseudonym.Subject
+		event.Subject = subject
+	default:
+		return nil, fmt.Errorf("%w: unsupported identifier type: %s", ErrInvalidRequest, getTokenRequest.Body.Identifier.Type)
 	}
 
-	now := time.Now()
+	now := ps.clock()
 
 	token := &pb.Token{
 		Subject:    subject,
-		Issuer:     *getTokenRequest.Body.Sender,
-		Audience:   *getTokenRequest.Body.Receiver,
+		Issuer:     sender,
+		Audience:   getTokenRequest.Body.Receiver,
 		Expiration: now.Add(time.Hour).Unix(),
 		IssuedAt:   now.Unix(),
-		Scopes:     []pb.Scope{pb.Scope_TREATMENT},
+		NotBefore:  now.Unix(),
+		Scopes:     []pb.Scope{scope},
+		SingleUse:  ps.singleUseTokens || (getTokenRequest.Body.SingleUse != nil && *getTokenRequest.Body.SingleUse),
 	}
 
-	tokenString, err := domain.CreateToken(token, key)
+	tokenString, err := ps.createToken(token)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	return GetToken200JSONResponse{GetTokenResponseJSONResponse{Token: &tokenString}}, nil
 }
+
+// OprfEvaluate evaluates an identifier blinded by the client with the OPRF key of the organisation.
+// The client unblinds the result to get a stable pseudonym, without the service ever seeing the identifier.
+func (ps *PseudonymService) OprfEvaluate(ctx context.Context, oprfEvaluateRequest OprfEvaluateRequestObject) (response OprfEvaluateResponseObject, err error) {
+	event := audit.Event{Operation: "oprfEvaluate", Scope: string(oprfEvaluateRequest.Body.Scope)}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
+	scope, err := ps.parseScope(oprfEvaluateRequest.Body.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := ps.checkGrantedScope(ctx, oprfEvaluateRequest.Body.Scope); err != nil {
+		return nil, err
+	}
+
+	audience, err := ps.callerOrganisation(ctx, oprfEvaluateRequest.Body.Organisation)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = audience
+	event.Audience = audience
+	if err := ps.authorise(audience, oprfEvaluateRequest.Body.Scope, policy.OPRF); err != nil {
+		return nil, err
+	}
+	if err := ps.checkPolicy(policy.Request{
+		Source:   string(BSN),
+		Target:   policy.OPRF,
+		Scope:    string(oprfEvaluateRequest.Body.Scope),
+		Caller:   audience,
+		Audience: audience,
+	}); err != nil {
+		return nil, err
+	}
+	if ps.revokedAudiences[audience] {
+		return nil, fmt.Errorf("%w: %s", ErrAudienceRevoked, audience)
+	}
+
+	keyring, err := ps.keyProvider.PseudonymKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	var keyID string
+	if oprfEvaluateRequest.Body.KeyId != nil {
+		keyID = *oprfEvaluateRequest.Body.KeyId
+	}
+	evaluatedElement, keyID, err := domain.EvaluateOPRF(oprfEvaluateRequest.Body.BlindedElement, keyID, audience, scope, keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	return OprfEvaluate200JSONResponse{OprfEvaluateResponseJSONResponse{
+		EvaluatedElement: &evaluatedElement,
+		KeyId:            &keyID,
+	}}, nil
+}
+
+// ExplainPolicy reports whether the policy permits an exchange and which rule decided, without performing the exchange.
+func (ps *PseudonymService) ExplainPolicy(ctx context.Context, explainPolicyRequest ExplainPolicyRequestObject) (response ExplainPolicyResponseObject, err error) {
+	event := audit.Event{Operation: "explainPolicy", Scope: string(explainPolicyRequest.Body.Scope)}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
+	caller, err := ps.callerOrganisation(ctx, explainPolicyRequest.Body.Caller)
+	if err != nil {
+		return nil, err
+	}
+
+	audience := caller
+	if explainPolicyRequest.Body.Audience != nil {
+		audience = *explainPolicyRequest.Body.Audience
+	}
+	event.Caller = caller
+	event.Audience = audience
+
+	decision := ps.evaluatePolicy(policy.Request{
+		Source:   explainPolicyRequest.Body.SourceType,
+		Target:   explainPolicyRequest.Body.TargetType,
+		Scope:    string(explainPolicyRequest.Body.Scope),
+		Caller:   caller,
+		Audience: audience,
+	})
+
+	explanation := ExplainPolicyResponseJSONResponse{
+		Allowed: decision.Allowed,
+		Reason:  decision.Reason,
+	}
+	if decision.Rule != "" {
+		explanation.Rule = &decision.Rule
+	}
+	return ExplainPolicy200JSONResponse{explanation}, nil
+}
+
+// RevokeToken revokes a token, after which it can not be exchanged anymore. Only the issuer and the audience of the
+// token can revoke it.
+func (ps *PseudonymService) RevokeToken(ctx context.Context, revokeTokenRequest RevokeTokenRequestObject) (response RevokeTokenResponseObject, err error) {
+	event := audit.Event{Operation: "revokeToken"}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
+
+	organisation, err := ps.callerOrganisation(ctx, revokeTokenRequest.Body.Organisation)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = organisation
+
+	keyring, err := ps.keyProvider.TokenKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	// an expired token is accepted, it may be revoked within the leeway of its lifetime
+	token, err := domain.DecryptToken(revokeTokenRequest.Body.Token, keyring)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	event.Audience = token.Audience
+	event.Subject = token.Subject
+
+	if organisation != token.Issuer && organisation != token.Audience {
+		return nil, fmt.Errorf("%w: %s", ErrRevocationNotPermitted, organisation)
+	}
+	if token.Jti == "" {
+		return nil, fmt.Errorf("%w: token has no id, revoke its issuer instead", ErrInvalidRequest)
+	}
+
+	if err := ps.revocations.RevokeToken(token.Jti, time.Unix(token.Expiration, 0).Add(ps.leeway)); err != nil {
+		return nil, err
+	}
+	return RevokeToken204Response{}, nil
+}
+
+// RevokeIssuer revokes all tokens the organisation has issued until now, e.g. after an incident. Tokens issued after
+// the revocation can be exchanged again.
+// Only an authenticated organisation can revoke its own tokens.
+func (ps *PseudonymService) RevokeIssuer(ctx context.Context, revokeIssuerRequest RevokeIssuerRequestObject) (response RevokeIssuerResponseObject, err error) {
+	event := audit.Event{Operation: "revokeIssuer"}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
+
+	// the issuer is never taken from the request, otherwise any caller could revoke the tokens of every issuer
+	if _, ok := auth.IdentityFromContext(ctx); !ok {
+		return nil, fmt.Errorf("%w: revoking an issuer requires an authenticated organisation", auth.ErrUnauthenticated)
+	}
+	issuer, err := ps.callerOrganisation(ctx, &revokeIssuerRequest.Body.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = issuer
+
+	if err := ps.revocations.RevokeIssuer(issuer, ps.clock()); err != nil {
+		return nil, err
+	}
+	return RevokeIssuer204Response{}, nil
+}
+
+// BumpPseudonymVersion bumps the version of the pseudonyms of a subject for an audience, after a pseudonym has been
+// compromised. The audience gets a new pseudonym for the subject, and its old pseudonyms are rejected.
+func (ps *PseudonymService) BumpPseudonymVersion(ctx context.Context, bumpRequest BumpPseudonymVersionRequestObject) (response BumpPseudonymVersionResponseObject, err error) {
+	event := audit.Event{Operation: "bumpPseudonymVersion", Audience: bumpRequest.Body.Audience}
+	defer func() {
+		event.Err = err
+		if auditErr := ps.recordAudit(event); auditErr != nil {
+			response, err = nil, auditErr
+		}
+	}()
+
+	organisation, err := ps.callerOrganisation(ctx, bumpRequest.Body.Organisation)
+	if err != nil {
+		return nil, err
+	}
+	event.Caller = organisation
+
+	if err := ps.authoriseAdmin(organisation); err != nil {
+		return nil, err
+	}
+
+	var subject string
+	switch bumpRequest.Body.Identifier.Type {
+	case BSN:
+		subject = bumpRequest.Body.Identifier.Value
+	case ORGANISATIONPSEUDO:
+		if bumpRequest.Body.Scope == nil {
+			return nil, fmt.Errorf("%w: scope is required for pseudonyms", ErrInvalidRequest)
+		}
+		event.Scope = string(*bumpRequest.Body.Scope)
+		scope, err := ps.parseScope(*bumpRequest.Body.Scope)
+		if err != nil {
+			return nil, err
+		}
+		if err := ps.checkGrantedScope(ctx, *bumpRequest.Body.Scope); err != nil {
+			return nil, err
+		}
+		pseudonym, err := ps.decryptPseudonym(bumpRequest.Body.Identifier.Value, bumpRequest.Body.Audience, scope)
+		if err != nil {
+			return nil, err
+		}
+		subject = pseudonym.Subject
+	default:
+		return nil, fmt.Errorf("%w: unsupported identifier type: %s", ErrInvalidRequest, bumpRequest.Body.Identifier.Type)
+	}
+	event.Subject = subject
+
+	version, err := ps.versions.Bump(subject, bumpRequest.Body.Audience)
+	if err != nil {
+		return nil, err
+	}
+	return BumpPseudonymVersion200JSONResponse{BumpPseudonymVersionResponseJSONResponse{Version: version}}, nil
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/audit"
+	"github.com/stevenvegt/pseudonyms/auth"
+	"github.com/stevenvegt/pseudonyms/domain"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"github.com/stevenvegt/pseudonyms/registry"
+	"github.com/stevenvegt/pseudonyms/revocation"
+	"google.golang.org/protobuf/proto"
+)
+
+// memoryLog is an audit log which keeps the events in memory.
+type memoryLog []audit.Event
+
+func (l *memoryLog) Record(event audit.Event) error {
+	*l = append(*l, event)
+	return nil
+}
+
+// newTestHandler returns the HTTP handler of a service with fixed keys, wired as in main.
+func newTestHandler(t *testing.T, opts ...Option) http.Handler {
+	t.Helper()
+	keyProvider, err := keys.NewMemoryKeyProvider(
+		keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32)),
+		keys.NewSingleKeyring(bytes.Repeat([]byte{2}, 32)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewPseudonymService(keyProvider, opts...)
+	strictHandler := NewStrictHandlerWithOptions(server, []StrictMiddlewareFunc{NegotiateContent}, ProblemHandlerOptions())
+	validator, err := NewRequestValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator(HandlerWithOptions(strictHandler, StdHTTPServerOptions{BaseRouter: http.NewServeMux()}))
+}
+
+// withIdentity authenticates the callers of the handler as the identity.
+func withIdentity(handler http.Handler, identity auth.Identity) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(auth.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// post sends a JSON request to the handler.
+func post(t *testing.T, handler http.Handler, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// getToken issues a token of ura:123 for ura:456 through the handler.
+func getToken(t *testing.T, handler http.Handler) string {
+	t.Helper()
+	response := post(t, handler, "/getToken", map[string]any{
+		"identifier": map[string]string{"type": "BSN", "value": "123456789"},
+		"sender":     "ura:123",
+		"receiver":   "ura:456",
+		"scope":      "zorg",
+	})
+	if response.Code != http.StatusOK {
+		t.Fatalf("getToken: %d %s", response.Code, response.Body)
+	}
+	body := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(response.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Token
+}
+
+func TestExchangeMalformedToken(t *testing.T) {
+	auditLog := &memoryLog{}
+	handler := newTestHandler(t, WithAuditLog(auditLog))
+
+	// a token container of which the nonce is cut to 1 byte
+	tokenString := getToken(t, handler)
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(tokenString, "prs1.tok."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	container := &pb.Container{}
+	if err := proto.Unmarshal(data, container); err != nil {
+		t.Fatal(err)
+	}
+	container.Nonce = container.Nonce[:1]
+	data, err = proto.Marshal(container)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortNonce := "prs1.tok." + base64.RawURLEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "short nonce", token: shortNonce},
+		{name: "not base64", token: "prs1.tok.!!!"},
+		{name: "unknown prefix", token: "prs9.tok.AAAA"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			*auditLog = nil
+			response := post(t, handler, "/exchangeToken", map[string]any{
+				"token":          test.token,
+				"identifierType": "BSN",
+				"scope":          "zorg",
+				"organisation":   "ura:456",
+			})
+			if response.Code != http.StatusUnauthorized {
+				t.Fatalf("expected status %d, got %d %s", http.StatusUnauthorized, response.Code, response.Body)
+			}
+			if contentType := response.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Fatalf("expected a problem, got %s", contentType)
+			}
+			if len(*auditLog) != 1 || (*auditLog)[0].Err == nil {
+				t.Fatalf("expected the failed exchange in the audit log, got %+v", *auditLog)
+			}
+		})
+	}
+}
+
+func TestBumpPseudonymVersionAdmin(t *testing.T) {
+	organisations, err := registry.New([]registry.Organisation{
+		{ID: "ura:1", Admin: true},
+		{ID: "ura:2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		organisation string
+		status       int
+	}{
+		{name: "no registry", organisation: "ura:1", status: http.StatusForbidden},
+		{name: "administrator", opts: []Option{WithRegistry(organisations)}, organisation: "ura:1", status: http.StatusOK},
+		{name: "not an administrator", opts: []Option{WithRegistry(organisations)}, organisation: "ura:2", status: http.StatusForbidden},
+		{name: "unknown organisation", opts: []Option{WithRegistry(organisations)}, organisation: "ura:3", status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(t, newTestHandler(t, test.opts...), "/admin/bumpPseudonymVersion", map[string]any{
+				"identifier":   map[string]string{"type": "BSN", "value": "123456789"},
+				"audience":     "ura:456",
+				"organisation": test.organisation,
+			})
+			if response.Code != test.status {
+				t.Fatalf("expected status %d, got %d %s", test.status, response.Code, response.Body)
+			}
+		})
+	}
+}
+
+func TestRevokeIssuer(t *testing.T) {
+	revocations := revocation.NewMemoryStore()
+	handler := newTestHandler(t, WithRevocationStore(revocations))
+	tokenString := getToken(t, handler)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		status  int
+	}{
+		{name: "not authenticated", handler: handler, status: http.StatusUnauthorized},
+		{name: "another organisation", handler: withIdentity(handler, auth.Identity{Organisation: "ura:456"}), status: http.StatusForbidden},
+		{name: "issuer", handler: withIdentity(handler, auth.Identity{Organisation: "ura:123"}), status: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(t, test.handler, "/revokeIssuer", map[string]any{"issuer": "ura:123"})
+			if response.Code != test.status {
+				t.Fatalf("expected status %d, got %d %s", test.status, response.Code, response.Body)
+			}
+		})
+	}
+
+	// the tokens issued before the revocation are rejected
+	response := post(t, handler, "/exchangeToken", map[string]any{
+		"token":          tokenString,
+		"identifierType": "BSN",
+		"scope":          "zorg",
+		"organisation":   "ura:456",
+	})
+	if response.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for a token of a revoked issuer, got %d %s", http.StatusUnauthorized, response.Code, response.Body)
+	}
+}
+
+func TestExchangeIdentifierEmptyStudy(t *testing.T) {
+	handler := newTestHandler(t)
+	response := post(t, handler, "/exchangeIdentifier", map[string]any{
+		"identifier":              map[string]string{"type": "BSN", "value": "123456789"},
+		"recipientIdentifierType": "RESEARCH_PSEUDO",
+		"organisation":            "ura:456",
+		"scope":                   "onderzoek",
+		"study":                   "",
+	})
+	if response.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d %s", http.StatusBadRequest, response.Code, response.Body)
+	}
+
+	// without the request validator the service rejects the empty study as well
+	_, err := domain.CreateResearchPseudonym("123456789", "ura:456", "", keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32)))
+	if status := problemStatus(err); status != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d for %v", http.StatusBadRequest, status, err)
+	}
+}
+
+func TestExchangeSingleUseTokenAfterFailure(t *testing.T) {
+	handler := newTestHandler(t, WithSingleUseTokens())
+	tokenString := getToken(t, handler)
+
+	tests := []struct {
+		name           string
+		identifierType string
+		status         int
+	}{
+		{name: "failed exchange", identifierType: "RESEARCH_PSEUDO", status: http.StatusBadRequest},
+		{name: "exchange", identifierType: "BSN", status: http.StatusOK},
+		{name: "replay", identifierType: "BSN", status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(t, handler, "/exchangeToken", map[string]any{
+				"token":          tokenString,
+				"identifierType": test.identifierType,
+				"scope":          "zorg",
+				"organisation":   "ura:456",
+			})
+			if response.Code != test.status {
+				t.Fatalf("expected status %d, got %d %s", test.status, response.Code, response.Body)
+			}
+		})
+	}
+}
+
+func TestExchangeTokenOrganisationMismatch(t *testing.T) {
+	handler := newTestHandler(t)
+	tokenString := getToken(t, handler)
+
+	tests := []struct {
+		name         string
+		identity     auth.Identity
+		organisation string
+		status       int
+	}{
+		{name: "same organisation", identity: auth.Identity{Organisation: "ura:456"}, organisation: "ura:456", status: http.StatusOK},
+		{name: "organisation of the certificate", identity: auth.Identity{Organisation: "ura:456"}, status: http.StatusOK},
+		{name: "other organisation", identity: auth.Identity{Organisation: "ura:789"}, organisation: "ura:456", status: http.StatusForbidden},
+		// the token is not issued for the authenticated organisation
+		{name: "other certificate", identity: auth.Identity{Organisation: "ura:789"}, status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := map[string]any{
+				"token":          tokenString,
+				"identifierType": "BSN",
+				"scope":          "zorg",
+			}
+			if test.organisation != "" {
+				body["organisation"] = test.organisation
+			}
+			response := post(t, withIdentity(handler, test.identity), "/exchangeToken", body)
+			if response.Code != test.status {
+				t.Fatalf("expected status %d, got %d %s", test.status, response.Code, response.Body)
+			}
+		})
+	}
+}
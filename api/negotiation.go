@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/stevenvegt/pseudonyms/cose"
+	"github.com/stevenvegt/pseudonyms/domain"
+)
+
+// mediaTypeCBOR is the media type of CBOR responses, in which COSE messages are byte strings.
+const mediaTypeCBOR = "application/cbor"
+
+// NegotiateContent is a strict middleware which responds with CBOR instead of JSON when the client prefers it in the
+// Accept header. Only the encoding of the response is negotiated, tokens and pseudonyms are in the format of their
+// receiver and COSE messages are encoded as byte strings. Operations without a CBOR response respond with JSON.
+func NegotiateContent(f StrictHandlerFunc, operationID string) StrictHandlerFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, request interface{}) (interface{}, error) {
+		if !prefersCBOR(r.Header.Get("Accept")) {
+			return f(ctx, w, r, request)
+		}
+
+		response, err := f(ctx, w, r, request)
+		if err != nil {
+			return nil, err
+		}
+		return cborResponse(response)
+	}
+}
+
+// prefersCBOR reports whether CBOR has a higher quality than JSON in the Accept header, JSON is preferred on a tie.
+func prefersCBOR(accept string) bool {
+	cborQuality, jsonQuality := 0.0, 0.0
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil {
+			quality = q
+		}
+		switch mediaType {
+		case mediaTypeCBOR:
+			cborQuality = max(cborQuality, quality)
+		case "application/json", "application/*", "*/*":
+			jsonQuality = max(jsonQuality, quality)
+		}
+	}
+	return cborQuality > jsonQuality
+}
+
+// cborResponse converts the JSON response of an operation to CBOR.
+func cborResponse(response interface{}) (interface{}, error) {
+	var body map[string]any
+	switch response := response.(type) {
+	case GetToken200JSONResponse:
+		body = map[string]any{"token": cborValue(*response.Token)}
+	case ExchangeToken200JSONResponse:
+		body = map[string]any{"identifier": cborIdentifier(response.Identifier)}
+	case ExchangeIdentifier200JSONResponse:
+		body = map[string]any{"identifier": cborIdentifier(response.Identifier)}
+	default:
+		return response, nil
+	}
+
+	data, err := cose.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.(type) {
+	case GetToken200JSONResponse:
+		return GetToken200ApplicationcborResponse{GetTokenResponseApplicationcborResponse{
+			Body:          bytes.NewReader(data),
+			ContentLength: int64(len(data)),
+		}}, nil
+	case ExchangeToken200JSONResponse:
+		return ExchangeToken200ApplicationcborResponse{ExchangeTokenResponseApplicationcborResponse{
+			Body:          bytes.NewReader(data),
+			ContentLength: int64(len(data)),
+		}}, nil
+	default:
+		return ExchangeIdentifier200ApplicationcborResponse{ExchangeIdentifierResponseApplicationcborResponse{
+			Body:          bytes.NewReader(data),
+			ContentLength: int64(len(data)),
+		}}, nil
+	}
+}
+
+func cborIdentifier(identifier *Identifier) map[string]any {
+	return map[string]any{"type": identifier.Type, "value": cborValue(identifier.Value)}
+}
+
+// cborValue returns a COSE message as a byte string, other values are returned as text strings.
+func cborValue(value string) any {
+	if data, ok := domain.DecodeCOSE(value); ok {
+		return data
+	}
+	return value
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// NewRequestValidator validates the embedded OpenAPI document and returns a middleware which checks every request
+// against it, e.g. required fields, enums and formats. Invalid requests are rejected with problem details
+// before they reach the PseudonymService.
+func NewRequestValidator() (func(http.Handler) http.Handler, error) {
+	swagger, err := GetSwagger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := swagger.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	// the servers of the document are not part of the paths served by this handler
+	swagger.Servers = nil
+
+	router, err := legacy.NewRouter(swagger)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				status := http.StatusNotFound
+				var routeErr *routers.RouteError
+				if errors.As(err, &routeErr) && routeErr.Reason == routers.ErrMethodNotAllowed.Error() {
+					status = http.StatusMethodNotAllowed
+				}
+				writeProblem(w, r, status, err)
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				writeProblem(w, r, http.StatusBadRequest, validationError(err))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// validationError returns the reason a request is invalid without the schema, which is part of the error by default.
+func validationError(err error) error {
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidRequest, "/"+strings.Join(schemaErr.JSONPointer(), "/"), schemaErr.Reason)
+	}
+	return fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+}
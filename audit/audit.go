@@ -0,0 +1,93 @@
+// Package audit records every exchange of the pseudonym service in a tamper-evident log.
+// Every record contains the hash of the previous record, so removing or changing a record breaks the chain.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+)
+
+// ErrChainBroken is returned when a record of the log does not match the hash chain.
+var ErrChainBroken = errors.New("audit log hash chain is broken")
+
+const (
+	// OutcomeSuccess is the outcome of an exchange which succeeded.
+	OutcomeSuccess = "success"
+	// OutcomeFailure is the outcome of an exchange which failed or was denied.
+	OutcomeFailure = "failure"
+)
+
+// Event is an exchange to record. The subject is never written to the log, only a keyed hash of it.
+type Event struct {
+	Operation string
+	Caller    string
+	Audience  string
+	Scope     string
+	Subject   string
+	Err       error
+}
+
+// Log records events.
+type Log interface {
+	Record(event Event) error
+}
+
+// Record is an entry of the log.
+type Record struct {
+	Sequence  uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Caller    string    `json:"caller,omitempty"`
+	Audience  string    `json:"audience,omitempty"`
+	Scope     string    `json:"scope,omitempty"`
+	// SubjectHash is a keyed hash of the subject, which allows finding the records of a subject
+	// without the log containing the BSN.
+	SubjectHash string `json:"subjectHash,omitempty"`
+	Outcome     string `json:"outcome"`
+	Error       string `json:"error,omitempty"`
+	// PreviousHash is the hash of the previous record, empty for the first record.
+	PreviousHash string `json:"prev"`
+	// Hash is the hash of the record, including the hash of the previous record.
+	Hash string `json:"hash"`
+}
+
+// newRecord creates the record of an event, without the hashes of the chain.
+func newRecord(event Event, subjectKey []byte, now time.Time) Record {
+	record := Record{
+		Time:      now.UTC(),
+		Operation: event.Operation,
+		Caller:    event.Caller,
+		Audience:  event.Audience,
+		Scope:     event.Scope,
+		Outcome:   OutcomeSuccess,
+	}
+	if event.Subject != "" {
+		record.SubjectHash = SubjectHash(subjectKey, event.Subject)
+	}
+	if event.Err != nil {
+		record.Outcome = OutcomeFailure
+		record.Error = event.Err.Error()
+	}
+	return record
+}
+
+// SubjectHash returns the keyed hash of a subject as it is written to the log.
+func SubjectHash(subjectKey []byte, subject string) string {
+	return hex.EncodeToString(crypto.MAC(subjectKey, []byte(subject)))
+}
+
+// hash computes the hash of the record, which covers all fields except the hash itself.
+func (r Record) hash() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
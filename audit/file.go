@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileLog is an append-only log of JSON records, one per line.
+type FileLog struct {
+	mu           sync.Mutex
+	file         *os.File
+	subjectKey   []byte
+	clock        func() time.Time
+	sequence     uint64
+	previousHash string
+}
+
+var _ Log = (*FileLog)(nil)
+
+// OpenFileLog opens the log in the file, which is created when it does not exist.
+// The records already in the file are verified, new records are appended to the chain.
+func OpenFileLog(path string, subjectKey []byte) (*FileLog, error) {
+	if len(subjectKey) < 16 {
+		return nil, fmt.Errorf("audit subject key must be at least 16 bytes")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := verify(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &FileLog{
+		file:         file,
+		subjectKey:   subjectKey,
+		clock:        time.Now,
+		sequence:     last.Sequence,
+		previousHash: last.Hash,
+	}, nil
+}
+
+// Record appends the record of the event to the log and syncs it to disk.
+func (l *FileLog) Record(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record := newRecord(event, l.subjectKey, l.clock())
+	record.Sequence = l.sequence + 1
+	record.PreviousHash = l.previousHash
+
+	hash, err := record.hash()
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %v", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write audit record: %v", err)
+	}
+
+	l.sequence = record.Sequence
+	l.previousHash = record.Hash
+	return nil
+}
+
+// Close closes the file of the log.
+func (l *FileLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// VerifyFile verifies the hash chain of the log in the file and returns the number of records.
+func VerifyFile(path string) (uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	last, err := verify(file)
+	if err != nil {
+		return 0, err
+	}
+	return last.Sequence, nil
+}
+
+// verify checks that every record is the successor of the previous record and returns the last record.
+func verify(r io.Reader) (Record, error) {
+	last := Record{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		record := Record{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return last, fmt.Errorf("%w: line %d: %v", ErrChainBroken, line, err)
+		}
+		if record.Sequence != last.Sequence+1 || record.PreviousHash != last.Hash {
+			return last, fmt.Errorf("%w: line %d does not follow record %d", ErrChainBroken, line, last.Sequence)
+		}
+		hash, err := record.hash()
+		if err != nil {
+			return last, err
+		}
+		if hash != record.Hash {
+			return last, fmt.Errorf("%w: line %d has been modified", ErrChainBroken, line)
+		}
+		last = record
+	}
+	if err := scanner.Err(); err != nil {
+		return last, err
+	}
+	return last, nil
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeLog writes a log of three records and returns its lines.
+func writeLog(t *testing.T, path string) []string {
+	t.Helper()
+	log, err := OpenFileLog(path, bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := []Event{
+		{Operation: "getToken", Caller: "ura:123", Audience: "ura:456", Scope: "zorg", Subject: "123456789"},
+		{Operation: "exchangeToken", Caller: "ura:456", Audience: "ura:456", Scope: "zorg", Subject: "123456789"},
+		{Operation: "exchangeToken", Caller: "ura:789", Scope: "zorg", Err: errors.New("token is not issued for this organisation")},
+	}
+	for _, event := range events {
+		if err := log.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("123456789")) {
+		t.Fatal("expected the log not to contain the subject")
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// modifyRecord changes the caller of the record on the line, and recomputes its hash when rehash is set.
+func modifyRecord(t *testing.T, line string, rehash bool) string {
+	t.Helper()
+	record := Record{}
+	if err := json.Unmarshal([]byte(line), &record); err != nil {
+		t.Fatal(err)
+	}
+	record.Caller = "ura:999"
+	if rehash {
+		hash, err := record.hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		record.Hash = hash
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestVerifyFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(lines []string) []string
+		records uint64
+		wantErr bool
+	}{
+		{name: "intact", tamper: func(lines []string) []string { return lines }, records: 3},
+		{
+			name:    "modified record",
+			tamper:  func(lines []string) []string { lines[1] = modifyRecord(t, lines[1], false); return lines },
+			wantErr: true,
+		},
+		{
+			name:    "modified record with new hash",
+			tamper:  func(lines []string) []string { lines[1] = modifyRecord(t, lines[1], true); return lines },
+			wantErr: true,
+		},
+		// the chain can not detect a rewrite or removal of the last records, the number of records and the last hash
+		// must be checked against a copy kept elsewhere
+		{
+			name:    "modified last record with new hash",
+			tamper:  func(lines []string) []string { lines[2] = modifyRecord(t, lines[2], true); return lines },
+			records: 3,
+		},
+		{name: "truncated", tamper: func(lines []string) []string { return lines[:2] }, records: 2},
+		{name: "removed record", tamper: func(lines []string) []string { return append(lines[:1], lines[2:]...) }, wantErr: true},
+		{name: "removed first record", tamper: func(lines []string) []string { return lines[1:] }, wantErr: true},
+		{
+			name:    "swapped records",
+			tamper:  func(lines []string) []string { lines[1], lines[2] = lines[2], lines[1]; return lines },
+			wantErr: true,
+		},
+		{name: "duplicated record", tamper: func(lines []string) []string { return append(lines, lines[2]) }, wantErr: true},
+		{name: "invalid JSON", tamper: func(lines []string) []string { return append(lines, "{") }, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			lines := test.tamper(writeLog(t, path))
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := VerifyFile(path)
+			if test.wantErr {
+				if !errors.Is(err, ErrChainBroken) {
+					t.Fatalf("expected %v, got %v", ErrChainBroken, err)
+				}
+				// a broken log is not appended to
+				if _, err := OpenFileLog(path, bytes.Repeat([]byte{1}, 32)); !errors.Is(err, ErrChainBroken) {
+					t.Fatalf("expected %v when opening the log, got %v", ErrChainBroken, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if records != test.records {
+				t.Fatalf("expected %d records, got %d", test.records, records)
+			}
+		})
+	}
+}
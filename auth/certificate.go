@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	oidSubjectAltName = asn1.ObjectIdentifier{2, 5, 29, 17}
+	// oidUZI is the type of the otherName in the subject alternative name of UZI certificates.
+	oidUZI = asn1.ObjectIdentifier{2, 5, 5, 5}
+)
+
+// uraField is the index of the URA of the organisation in the UZI otherName, which has the form
+// <OID CA>-<version>-<UZI number>-<card type>-<URA>-<role>-<AGB>.
+const uraField = 4
+
+type otherName struct {
+	TypeID asn1.ObjectIdentifier
+	Value  asn1.RawValue `asn1:"tag:0,explicit"`
+}
+
+// OrganisationFromCertificate returns the organisation of an UZI certificate as ura:<URA>.
+// The URA is taken from the otherName of type 2.5.5.5 in the subject alternative name.
+func OrganisationFromCertificate(cert *x509.Certificate) (string, error) {
+	names, err := otherNames(cert)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range names {
+		if !name.TypeID.Equal(oidUZI) {
+			continue
+		}
+		fields := strings.Split(string(name.Value.Bytes), "-")
+		if len(fields) <= uraField || fields[uraField] == "" {
+			return "", fmt.Errorf("invalid UZI otherName: %s", name.Value.Bytes)
+		}
+		return "ura:" + fields[uraField], nil
+	}
+
+	return "", fmt.Errorf("certificate has no UZI otherName")
+}
+
+// otherNames returns the otherNames of the subject alternative name, which are not parsed by crypto/x509.
+func otherNames(cert *x509.Certificate) ([]otherName, error) {
+	var names []otherName
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSubjectAltName) {
+			continue
+		}
+
+		var generalNames asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &generalNames); err != nil {
+			return nil, fmt.Errorf("invalid subject alternative name: %v", err)
+		}
+
+		data := generalNames.Bytes
+		for len(data) > 0 {
+			var generalName asn1.RawValue
+			rest, err := asn1.Unmarshal(data, &generalName)
+			if err != nil {
+				return nil, fmt.Errorf("invalid subject alternative name: %v", err)
+			}
+			data = rest
+
+			// otherName is the general name with context specific tag 0
+			if generalName.Class != asn1.ClassContextSpecific || generalName.Tag != 0 {
+				continue
+			}
+			var name otherName
+			if _, err := asn1.UnmarshalWithParams(generalName.FullBytes, &name, "tag:0"); err != nil {
+				return nil, fmt.Errorf("invalid otherName: %v", err)
+			}
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// ClientCertificate returns a middleware which authenticates the caller with the client certificate of the TLS
+// connection and adds its identity to the context of the request. The certificate must be verified by the TLS server,
+// e.g. with tls.RequireAndVerifyClientCert. Requests which can not be authenticated are passed to onError.
+func ClientCertificate(onError func(http.ResponseWriter, *http.Request, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				onError(w, r, fmt.Errorf("%w: no verified client certificate", ErrUnauthenticated))
+				return
+			}
+
+			organisation, err := OrganisationFromCertificate(r.TLS.VerifiedChains[0][0])
+			if err != nil {
+				onError(w, r, fmt.Errorf("%w: %w", ErrUnauthenticated, err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), Identity{Organisation: organisation})))
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// uziCertificate returns a certificate of which the subject alternative name holds the general names.
+func uziCertificate(t *testing.T, generalNames ...asn1.RawValue) *x509.Certificate {
+	t.Helper()
+	var data []byte
+	for _, name := range generalNames {
+		encoded, err := asn1.Marshal(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, encoded...)
+	}
+	value, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &x509.Certificate{Extensions: []pkix.Extension{{Id: oidSubjectAltName, Value: value}}}
+}
+
+// otherNameOf returns an otherName general name of the type with an IA5String value.
+func otherNameOf(t *testing.T, typeID asn1.ObjectIdentifier, value string) asn1.RawValue {
+	t.Helper()
+	str, err := asn1.MarshalWithParams(value, "ia5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := asn1.MarshalWithParams(struct {
+		TypeID asn1.ObjectIdentifier
+		Value  asn1.RawValue
+	}{
+		TypeID: typeID,
+		Value:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: str},
+	}, "tag:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return asn1.RawValue{FullBytes: name}
+}
+
+func TestOrganisationFromCertificate(t *testing.T) {
+	dnsName := asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte("example.com")}
+	const uzi = "2.16.528.1.1007.99.2110-1-900012345-S-90000123-00.000-00000000"
+
+	tests := []struct {
+		name         string
+		cert         *x509.Certificate
+		organisation string
+		wantErr      bool
+	}{
+		{name: "UZI", cert: uziCertificate(t, otherNameOf(t, oidUZI, uzi)), organisation: "ura:90000123"},
+		{name: "UZI after DNS name", cert: uziCertificate(t, dnsName, otherNameOf(t, oidUZI, uzi)), organisation: "ura:90000123"},
+		{
+			name:         "UZI after other otherName",
+			cert:         uziCertificate(t, otherNameOf(t, asn1.ObjectIdentifier{1, 2, 3}, "other"), otherNameOf(t, oidUZI, uzi)),
+			organisation: "ura:90000123",
+		},
+		{name: "too few fields", cert: uziCertificate(t, otherNameOf(t, oidUZI, "2.16.528.1.1007.99.2110-1-900012345-S")), wantErr: true},
+		{name: "empty URA", cert: uziCertificate(t, otherNameOf(t, oidUZI, "2.16.528.1.1007.99.2110-1-900012345-S--00.000-00000000")), wantErr: true},
+		{name: "other otherName", cert: uziCertificate(t, otherNameOf(t, asn1.ObjectIdentifier{1, 2, 3}, uzi)), wantErr: true},
+		{name: "DNS name only", cert: uziCertificate(t, dnsName), wantErr: true},
+		{name: "no subject alternative name", cert: &x509.Certificate{}, wantErr: true},
+		{name: "invalid subject alternative name", cert: &x509.Certificate{Extensions: []pkix.Extension{{Id: oidSubjectAltName, Value: []byte{0x30, 0x05}}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organisation, err := OrganisationFromCertificate(test.cert)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", organisation)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if organisation != test.organisation {
+				t.Fatalf("expected %s, got %s", test.organisation, organisation)
+			}
+		})
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	cert := uziCertificate(t, otherNameOf(t, oidUZI, "2.16.528.1.1007.99.2110-1-900012345-S-90000123-00.000-00000000"))
+
+	tests := []struct {
+		name         string
+		tls          *tls.ConnectionState
+		organisation string
+		wantErr      bool
+	}{
+		{name: "UZI certificate", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}, organisation: "ura:90000123"},
+		{name: "no TLS", wantErr: true},
+		{name: "not verified", tls: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, wantErr: true},
+		{name: "no UZI otherName", tls: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				identity Identity
+				authErr  error
+			)
+			middleware := ClientCertificate(func(w http.ResponseWriter, r *http.Request, err error) {
+				authErr = err
+			})
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = IdentityFromContext(r.Context())
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/exchangeToken", nil)
+			request.TLS = test.tls
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+
+			if test.wantErr {
+				if !errors.Is(authErr, ErrUnauthenticated) {
+					t.Fatalf("expected %v, got %v", ErrUnauthenticated, authErr)
+				}
+				return
+			}
+			if authErr != nil {
+				t.Fatal(authErr)
+			}
+			if identity.Organisation != test.organisation {
+				t.Fatalf("expected %s, got %s", test.organisation, identity.Organisation)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+// Package auth authenticates the organisations calling the pseudonym service.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// ErrUnauthenticated is returned when the caller of the service could not be authenticated.
+var ErrUnauthenticated = errors.New("caller is not authenticated")
+
+// Identity is the authenticated identity of the caller of the service.
+type Identity struct {
+	// Organisation is the identifier of the organisation of the caller, e.g. ura:12345678.
+	Organisation string
+	// Scopes are the scopes granted to the caller, e.g. by an access token. Nil means the caller is not restricted
+	// to scopes, e.g. when it is authenticated with a client certificate.
+	Scopes []string
+}
+
+// HasScope reports whether the scope is granted to the caller.
+func (i Identity) HasScope(scope string) bool {
+	return i.Scopes == nil || slices.Contains(i.Scopes, scope)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of the context which holds the identity of the caller.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the caller, if the caller is authenticated.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
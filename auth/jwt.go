@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+// signatureAlgorithms are the algorithms accepted for the signature of access tokens.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// JWTConfig configures the verification of JWT access tokens.
+type JWTConfig struct {
+	// KeySet holds the public keys of the authorization server, the kid of a token selects the key.
+	KeySet jose.JSONWebKeySet
+	// Issuer is the expected iss claim of the tokens.
+	Issuer string
+	// Audience is the expected aud claim of the tokens, the identifier of this service.
+	Audience string
+	// OrganisationClaim is the claim holding the organisation of the caller, sub when empty.
+	OrganisationClaim string
+	// Leeway is the allowed clock skew when validating the lifetime of tokens.
+	Leeway time.Duration
+}
+
+// LoadJWKS reads a JWK Set with the public keys of the authorization server from a file.
+func LoadJWKS(path string) (jose.JSONWebKeySet, error) {
+	keySet := jose.JSONWebKeySet{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return keySet, err
+	}
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return keySet, fmt.Errorf("invalid JWK Set %s: %v", path, err)
+	}
+	if len(keySet.Keys) == 0 {
+		return keySet, fmt.Errorf("JWK Set %s contains no keys", path)
+	}
+	for _, key := range keySet.Keys {
+		if !key.IsPublic() {
+			return keySet, fmt.Errorf("JWK Set %s contains a private or symmetric key", path)
+		}
+	}
+	return keySet, nil
+}
+
+// VerifyAccessToken verifies the signature and claims of a JWT access token and returns the identity of the caller.
+// The scopes of the identity are taken from the space separated scope claim.
+func (c JWTConfig) VerifyAccessToken(accessToken string, now time.Time) (Identity, error) {
+	if c.Issuer == "" || c.Audience == "" {
+		return Identity{}, fmt.Errorf("issuer and audience of access tokens are not configured")
+	}
+
+	token, err := jwt.ParseSigned(accessToken, signatureAlgorithms)
+	if err != nil {
+		return Identity{}, fmt.Errorf("invalid access token: %v", err)
+	}
+
+	claims := jwt.Claims{}
+	custom := map[string]interface{}{}
+	if err := token.Claims(c.KeySet, &claims, &custom); err != nil {
+		return Identity{}, fmt.Errorf("invalid access token: %v", err)
+	}
+
+	expected := jwt.Expected{
+		Issuer:      c.Issuer,
+		AnyAudience: jwt.Audience{c.Audience},
+		Time:        now,
+	}
+	if err := claims.ValidateWithLeeway(expected, c.Leeway); err != nil {
+		return Identity{}, fmt.Errorf("invalid access token: %v", err)
+	}
+	if claims.Expiry == nil {
+		return Identity{}, fmt.Errorf("invalid access token: no expiration time")
+	}
+
+	organisationClaim := c.OrganisationClaim
+	if organisationClaim == "" {
+		organisationClaim = "sub"
+	}
+	organisation, _ := custom[organisationClaim].(string)
+	if organisation == "" {
+		return Identity{}, fmt.Errorf("invalid access token: no %s claim", organisationClaim)
+	}
+
+	// a token without scopes grants no scopes, which is different from an identity without scope restrictions
+	scopes := []string{}
+	if scope, ok := custom["scope"].(string); ok {
+		scopes = strings.Fields(scope)
+	}
+
+	return Identity{Organisation: organisation, Scopes: scopes}, nil
+}
+
+// BearerToken returns a middleware which authenticates the caller with a JWT access token in the Authorization header
+// and adds its identity to the context of the request. Requests which can not be authenticated are passed to onError.
+func BearerToken(config JWTConfig, onError func(http.ResponseWriter, *http.Request, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, accessToken, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || accessToken == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				onError(w, r, fmt.Errorf("%w: no bearer token", ErrUnauthenticated))
+				return
+			}
+
+			identity, err := config.VerifyAccessToken(accessToken, time.Now())
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				onError(w, r, fmt.Errorf("%w: %w", ErrUnauthenticated, err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
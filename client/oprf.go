@@ -0,0 +1,75 @@
+// Package client contains helpers for clients of the pseudonym service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gtank/ristretto255"
+	"github.com/stevenvegt/pseudonyms/api"
+	"github.com/stevenvegt/pseudonyms/crypto"
+)
+
+// OPRFPseudonym gets the pseudonym of an identifier, e.g. a BSN, for an organisation using the OPRF endpoint.
+// The identifier is blinded before it is sent, so the service never sees it.
+// The pseudonym is the base64url encoded output of the OPRF and is the same for every call with the same identifier
+// and key. The identifier is evaluated with the key with the key ID, or the active key when it is empty, and the ID of
+// the key is returned with the pseudonym. Keep it with the pseudonym, the pseudonym changes when the key is rotated.
+func OPRFPseudonym(ctx context.Context, httpClient *http.Client, baseURL string, identifier string, organisation string, scope string, keyID string) (string, string, error) {
+	input := []byte(identifier)
+
+	blind, blindedElement, err := crypto.OPRFBlind(input)
+	if err != nil {
+		return "", "", err
+	}
+
+	request := api.OprfEvaluateJSONRequestBody{
+		BlindedElement: blindedElement.Encode(nil),
+		Organisation:   &organisation,
+		Scope:          api.Scope(scope),
+	}
+	if keyID != "" {
+		request.KeyId = &keyID
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/oprf/evaluate", bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("OPRF evaluation failed with status %s", resp.Status)
+	}
+
+	evaluateResponse := api.OprfEvaluateResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&evaluateResponse); err != nil {
+		return "", "", err
+	}
+	if evaluateResponse.EvaluatedElement == nil || evaluateResponse.KeyId == nil {
+		return "", "", fmt.Errorf("OPRF response contains no evaluated element or key ID")
+	}
+
+	evaluatedElement := ristretto255.NewElement()
+	if err := evaluatedElement.Decode(*evaluateResponse.EvaluatedElement); err != nil {
+		return "", "", fmt.Errorf("invalid evaluated element: %v", err)
+	}
+
+	output := crypto.OPRFFinalize(input, blind, evaluatedElement)
+
+	return base64.RawURLEncoding.EncodeToString(output), *evaluateResponse.KeyId, nil
+}
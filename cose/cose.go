@@ -0,0 +1,146 @@
+// Package cose encodes and decodes COSE_Encrypt0 messages (RFC 9052) and CBOR Web Tokens (RFC 8392).
+// Only the structure of the messages is handled, the content is encrypted by the caller with the additional data of
+// the message.
+package cose
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ErrMalformed is returned when a message is not a valid COSE_Encrypt0 message.
+var ErrMalformed = errors.New("malformed COSE message")
+
+const (
+	// TagEncrypt0 is the CBOR tag of COSE_Encrypt0 messages.
+	TagEncrypt0 = 16
+	// TagCWT is the CBOR tag of CBOR Web Tokens.
+	TagCWT = 61
+)
+
+// Header labels of RFC 9052.
+const (
+	HeaderAlgorithm = 1
+	HeaderKeyID     = 4
+	HeaderIV        = 5
+)
+
+const (
+	// AlgorithmA256GCM is AES-GCM with a 256-bit key.
+	AlgorithmA256GCM int64 = 3
+	// AlgorithmAESGCMSIV is AES-GCM-SIV (RFC 8452), a deterministic AEAD. It is not registered, so it uses a value
+	// reserved for private use.
+	AlgorithmAESGCMSIV int64 = -65537
+)
+
+var (
+	// encMode encodes with the core deterministic encoding, so equal messages have equal encodings.
+	encMode, _ = cbor.CoreDetEncOptions().EncMode()
+	decMode, _ = cbor.DecOptions{DupMapKey: cbor.DupMapKeyEnforcedAPF}.DecMode()
+)
+
+// Marshal encodes a value with the core deterministic encoding.
+func Marshal(value any) ([]byte, error) {
+	return encMode.Marshal(value)
+}
+
+// Unmarshal decodes a value, maps with duplicate keys are rejected.
+func Unmarshal(data []byte, value any) error {
+	return decMode.Unmarshal(data, value)
+}
+
+// Encrypt0 is a COSE_Encrypt0 message. The algorithm is in the protected header, the key ID and IV are in the
+// unprotected header.
+type Encrypt0 struct {
+	Algorithm  int64
+	KeyID      []byte
+	IV         []byte
+	Ciphertext []byte
+	// CWT reports whether the message is a CBOR Web Token.
+	CWT bool
+
+	protected []byte
+}
+
+type encrypt0 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[int64]cbor.RawMessage
+	Ciphertext  []byte
+}
+
+// NewEncrypt0 creates a message for the algorithm, of which the ciphertext is set after encrypting the content with
+// the additional data of the message.
+func NewEncrypt0(algorithm int64, keyID []byte, iv []byte) (*Encrypt0, error) {
+	protected, err := Marshal(map[int64]any{HeaderAlgorithm: algorithm})
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypt0{Algorithm: algorithm, KeyID: keyID, IV: iv, protected: protected}, nil
+}
+
+// AdditionalData returns the Enc_structure of the message, which authenticates the protected header.
+func (m *Encrypt0) AdditionalData() ([]byte, error) {
+	return Marshal([]any{"Encrypt0", m.protected, []byte{}})
+}
+
+// MarshalCBOR encodes the message as a tagged COSE_Encrypt0 message, wrapped in the CWT tag for CBOR Web Tokens.
+func (m *Encrypt0) MarshalCBOR() ([]byte, error) {
+	unprotected := map[int64]any{HeaderKeyID: m.KeyID}
+	if m.IV != nil {
+		unprotected[HeaderIV] = m.IV
+	}
+	message := cbor.Tag{Number: TagEncrypt0, Content: []any{m.protected, unprotected, m.Ciphertext}}
+	if m.CWT {
+		return Marshal(cbor.Tag{Number: TagCWT, Content: message})
+	}
+	return Marshal(message)
+}
+
+// UnmarshalCBOR decodes a tagged COSE_Encrypt0 message, which may be wrapped in the CWT tag.
+func (m *Encrypt0) UnmarshalCBOR(data []byte) error {
+	tag := cbor.RawTag{}
+	if err := Unmarshal(data, &tag); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	m.CWT = tag.Number == TagCWT
+	if m.CWT {
+		if err := Unmarshal(tag.Content, &tag); err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+	if tag.Number != TagEncrypt0 {
+		return fmt.Errorf("%w: unexpected tag %d", ErrMalformed, tag.Number)
+	}
+
+	message := encrypt0{}
+	if err := Unmarshal(tag.Content, &message); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	protected := map[int64]cbor.RawMessage{}
+	if err := Unmarshal(message.Protected, &protected); err != nil {
+		return fmt.Errorf("%w: protected header: %v", ErrMalformed, err)
+	}
+	if err := Unmarshal(protected[HeaderAlgorithm], &m.Algorithm); err != nil {
+		return fmt.Errorf("%w: algorithm: %v", ErrMalformed, err)
+	}
+	if err := Unmarshal(message.Unprotected[HeaderKeyID], &m.KeyID); err != nil {
+		return fmt.Errorf("%w: key id: %v", ErrMalformed, err)
+	}
+	if iv, ok := message.Unprotected[HeaderIV]; ok {
+		if err := Unmarshal(iv, &m.IV); err != nil {
+			return fmt.Errorf("%w: iv: %v", ErrMalformed, err)
+		}
+	}
+	m.Ciphertext = message.Ciphertext
+	m.protected = message.Protected
+	return nil
+}
+
+// IsEncrypt0 reports whether the data starts with the tag of a COSE_Encrypt0 message or a CBOR Web Token.
+func IsEncrypt0(data []byte) bool {
+	return len(data) >= 2 && (data[0] == 0xd0 || (data[0] == 0xd8 && data[1] == TagCWT))
+}
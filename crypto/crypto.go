@@ -3,18 +3,56 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/agl/gcmsiv"
+	"golang.org/x/crypto/hkdf"
 )
 
-// Function to derive a deterministic nonce using SHA-256
-func deriveNonce(data []byte) []byte {
-	hash := sha256.Sum256(data)
-	return hash[:12] // AES-GCM-SIV requires a 12-byte nonce
+// ErrInvalidNonce is returned when the nonce of a ciphertext does not have the nonce size of the cipher.
+var ErrInvalidNonce = errors.New("invalid nonce")
+
+// gcmSIVNonceSize is the nonce size of AES-GCM-SIV (RFC 8452). The gcmsiv package reports 16 bytes, but only uses the
+// first 12 bytes of the nonce.
+const gcmSIVNonceSize = 12
+
+// nonceInfo is the HKDF info of the key which derives the nonces of AES-GCM-SIV from the encryption key.
+var nonceInfo = []byte("aes-gcm-siv nonce")
+
+// deriveNonce derives a deterministic nonce from the plaintext and additional data with an HMAC under a subkey of the
+// encryption key. The nonce is stored next to the ciphertext, so without the key it must not reveal the plaintext: an
+// unkeyed hash of a BSN can be found by trying all BSNs.
+func deriveNonce(key, plaintext, additionalData []byte) ([]byte, error) {
+	nonceKey, err := DeriveKey(key, nil, nonceInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, nonceKey)
+	mac.Write(plaintext)
+	mac.Write(additionalData)
+	return mac.Sum(nil)[:gcmSIVNonceSize], nil
+}
+
+// DeriveKey derives a subkey of the given length from a secret using HKDF-SHA256.
+// The info binds the subkey to its context, different info values result in independent keys.
+func DeriveKey(secret, salt, info []byte, length int) ([]byte, error) {
+	key := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key); err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	return key, nil
+}
+
+// MAC computes a HMAC-SHA256 of the data, a keyed one-way function.
+func MAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
 }
 
 func EncryptAESGCM_SIV(key []byte, plaintext []byte, additionalData []byte) ([]byte, []byte, error) {
@@ -24,7 +62,10 @@ func EncryptAESGCM_SIV(key []byte, plaintext []byte, additionalData []byte) ([]b
 		return nil, nil, fmt.Errorf("failed to create AES-GCM-SIV block cipher: %v", err)
 	}
 
-	nonce := deriveNonce(append(plaintext, additionalData...))
+	nonce, err := deriveNonce(key, plaintext, additionalData)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive nonce: %v", err)
+	}
 
 	ciphertext := aesGCNSIV.Seal(nil, nonce, plaintext, additionalData)
 
@@ -36,6 +77,9 @@ func DecryptAESGCM_SIV(key []byte, nonce, ciphertext []byte, additionalData []by
 	if err != nil {
 		return "", err
 	}
+	if len(nonce) != gcmSIVNonceSize {
+		return "", fmt.Errorf("%w: nonce is %d bytes, not %d", ErrInvalidNonce, len(nonce), gcmSIVNonceSize)
+	}
 
 	plaintext, err := aesGCNSIV.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
@@ -84,6 +128,9 @@ func DecryptAESGCM(key []byte, nonce, ciphertext, additionalData []byte) ([]byte
 	if err != nil {
 		return nil, err
 	}
+	if len(nonce) != aesGCM.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce is %d bytes, not %d", ErrInvalidNonce, len(nonce), aesGCM.NonceSize())
+	}
 
 	// Decrypt the ciphertext
 	plaintext, err := aesGCM.Open(nil, nonce, ciphertext, additionalData)
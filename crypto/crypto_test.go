@@ -0,0 +1,85 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+)
+
+func TestDecryptInvalidNonce(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	tests := []struct {
+		name    string
+		decrypt func(nonce []byte) error
+	}{
+		{
+			name: "AES-GCM",
+			decrypt: func(nonce []byte) error {
+				_, err := DecryptAESGCM(key, nonce, make([]byte, 32), nil)
+				return err
+			},
+		},
+		{
+			name: "AES-GCM-SIV",
+			decrypt: func(nonce []byte) error {
+				_, err := DecryptAESGCM_SIV(key, nonce, make([]byte, 32), nil)
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, size := range []int{0, 1, 3, 11, 13, 16} {
+				if err := test.decrypt(make([]byte, size)); !errors.Is(err, ErrInvalidNonce) {
+					t.Fatalf("expected %v for a nonce of %d bytes, got %v", ErrInvalidNonce, size, err)
+				}
+			}
+			// a nonce of the right size fails on the tag instead
+			if err := test.decrypt(make([]byte, 12)); err == nil || errors.Is(err, ErrInvalidNonce) {
+				t.Fatalf("expected an authentication error for a nonce of 12 bytes, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptAESGCM_SIVNonce(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	plaintext := []byte("123456789")
+	aad := []byte("header")
+
+	nonce, ciphertext, err := EncryptAESGCM_SIV(key, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nonce) != gcmSIVNonceSize {
+		t.Fatalf("expected a nonce of %d bytes, got %d", gcmSIVNonceSize, len(nonce))
+	}
+	decrypted, err := DecryptAESGCM_SIV(key, nonce, ciphertext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted != string(plaintext) {
+		t.Fatalf("expected %s, got %s", plaintext, decrypted)
+	}
+
+	// the nonce is deterministic, but cannot be computed from the plaintext without the key
+	again, _, err := EncryptAESGCM_SIV(key, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(nonce, again) {
+		t.Fatal("expected the same nonce for the same plaintext")
+	}
+	hash := sha256.Sum256(append(append([]byte{}, plaintext...), aad...))
+	if bytes.Equal(nonce, hash[:gcmSIVNonceSize]) {
+		t.Fatal("expected the nonce not to be a hash of the plaintext")
+	}
+	other, _, err := EncryptAESGCM_SIV(bytes.Repeat([]byte{2}, 32), plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(nonce, other) {
+		t.Fatal("expected another nonce for another key")
+	}
+}
@@ -0,0 +1,106 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"io"
+
+	"github.com/gtank/ristretto255"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ElGamal is an ElGamal ciphertext on the ristretto255 group, the building block of polymorphic pseudonyms.
+// It encrypts a message M for public key Y = y*G as B = r*G and C = M + r*Y.
+// The public key is part of the ciphertext so it can be re-keyed without knowing the private key.
+type ElGamal struct {
+	B *ristretto255.Element
+	C *ristretto255.Element
+	Y *ristretto255.Element
+}
+
+// RandomScalar returns a uniformly random scalar.
+func RandomScalar() (*ristretto255.Scalar, error) {
+	buf := make([]byte, 64)
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+		return nil, fmt.Errorf("failed to generate random scalar: %v", err)
+	}
+	return ristretto255.NewScalar().FromUniformBytes(buf), nil
+}
+
+// DeriveScalar derives a scalar from a secret using HKDF-SHA512, the info binds the scalar to its purpose.
+func DeriveScalar(secret, info []byte) (*ristretto255.Scalar, error) {
+	buf := make([]byte, 64)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, secret, nil, info), buf); err != nil {
+		return nil, fmt.Errorf("failed to derive scalar: %v", err)
+	}
+	return ristretto255.NewScalar().FromUniformBytes(buf), nil
+}
+
+// HashToElement maps data to a group element, nobody knows the discrete logarithm of the result.
+func HashToElement(data []byte) *ristretto255.Element {
+	hash := sha512.Sum512(data)
+	return ristretto255.NewElement().FromUniformBytes(hash[:])
+}
+
+// PublicKey returns the public key y*G of a private key y.
+func PublicKey(privateKey *ristretto255.Scalar) *ristretto255.Element {
+	return ristretto255.NewElement().ScalarBaseMult(privateKey)
+}
+
+// EncryptElGamal encrypts the message point m for public key y.
+func EncryptElGamal(m, y *ristretto255.Element) (*ElGamal, error) {
+	r, err := RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ElGamal{
+		B: ristretto255.NewElement().ScalarBaseMult(r),
+		C: ristretto255.NewElement().Add(m, ristretto255.NewElement().ScalarMult(r, y)),
+		Y: y,
+	}, nil
+}
+
+// DecryptElGamal decrypts the ciphertext with the private key belonging to its public key.
+func DecryptElGamal(eg *ElGamal, privateKey *ristretto255.Scalar) (*ristretto255.Element, error) {
+	if PublicKey(privateKey).Equal(eg.Y) != 1 {
+		return nil, fmt.Errorf("private key does not match the public key of the ciphertext")
+	}
+	return ristretto255.NewElement().Subtract(eg.C, ristretto255.NewElement().ScalarMult(privateKey, eg.B)), nil
+}
+
+// Rerandomize returns a new ciphertext of the same message which can not be linked to the original.
+func Rerandomize(eg *ElGamal) (*ElGamal, error) {
+	s, err := RandomScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ElGamal{
+		B: ristretto255.NewElement().Add(eg.B, ristretto255.NewElement().ScalarBaseMult(s)),
+		C: ristretto255.NewElement().Add(eg.C, ristretto255.NewElement().ScalarMult(s, eg.Y)),
+		Y: eg.Y,
+	}, nil
+}
+
+// Reshuffle multiplies the encrypted message by n, without decrypting it.
+// A ciphertext of M becomes a ciphertext of n*M.
+func Reshuffle(eg *ElGamal, n *ristretto255.Scalar) *ElGamal {
+	return &ElGamal{
+		B: ristretto255.NewElement().ScalarMult(n, eg.B),
+		C: ristretto255.NewElement().ScalarMult(n, eg.C),
+		Y: eg.Y,
+	}
+}
+
+// Rekey changes the key the ciphertext is encrypted for, without decrypting it.
+// A ciphertext for private key y can be decrypted with private key k*y afterwards.
+func Rekey(eg *ElGamal, k *ristretto255.Scalar) *ElGamal {
+	kInv := ristretto255.NewScalar().Invert(k)
+	return &ElGamal{
+		B: ristretto255.NewElement().ScalarMult(kInv, eg.B),
+		C: eg.C,
+		Y: ristretto255.NewElement().ScalarMult(k, eg.Y),
+	}
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/gtank/ristretto255"
+)
+
+func TestElGamalRoundTrip(t *testing.T) {
+	privateKey, err := RandomScalar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := HashToElement([]byte("123456789"))
+
+	eg, err := EncryptElGamal(m, PublicKey(privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptElGamal(eg, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Equal(m) != 1 {
+		t.Fatal("decrypted message does not match")
+	}
+}
+
+func TestElGamalWrongKey(t *testing.T) {
+	privateKey, _ := RandomScalar()
+	otherKey, _ := RandomScalar()
+
+	eg, err := EncryptElGamal(HashToElement([]byte("123456789")), PublicKey(privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptElGamal(eg, otherKey); err == nil {
+		t.Fatal("expected an error for another private key")
+	}
+}
+
+func TestRerandomize(t *testing.T) {
+	privateKey, _ := RandomScalar()
+	m := HashToElement([]byte("123456789"))
+
+	eg, err := EncryptElGamal(m, PublicKey(privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rerandomized, err := Rerandomize(eg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rerandomized.B.Equal(eg.B) == 1 || rerandomized.C.Equal(eg.C) == 1 {
+		t.Fatal("rerandomized ciphertext can be linked to the original")
+	}
+
+	decrypted, err := DecryptElGamal(rerandomized, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Equal(m) != 1 {
+		t.Fatal("decrypted message does not match")
+	}
+}
+
+// TestReshuffleRekey checks that a ciphertext of M for key y, reshuffled with n and re-keyed with k,
+// decrypts to n*M with key k*y.
+func TestReshuffleRekey(t *testing.T) {
+	privateKey, _ := RandomScalar()
+	n, _ := RandomScalar()
+	k, _ := RandomScalar()
+	m := HashToElement([]byte("123456789"))
+
+	eg, err := EncryptElGamal(m, PublicKey(privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transformed, err := Rerandomize(Rekey(Reshuffle(eg, n), k))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecryptElGamal(transformed, privateKey); err == nil {
+		t.Fatal("expected an error for the key before re-keying")
+	}
+	decrypted, err := DecryptElGamal(transformed, ristretto255.NewScalar().Multiply(k, privateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.Equal(ristretto255.NewElement().ScalarMult(n, m)) != 1 {
+		t.Fatal("decrypted message is not the reshuffled message")
+	}
+}
@@ -0,0 +1,135 @@
+package crypto
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gtank/ristretto255"
+)
+
+// OPRF implements the base mode of the oblivious pseudorandom function of RFC 9497 with the ristretto255-SHA512 suite.
+// The client blinds its input, the server evaluates the blinded input with its key and the client unblinds the
+// result. The server never learns the input and the client never learns the key.
+
+// oprfContextString is the context string of the OPRF mode with the ristretto255-SHA512 suite.
+const oprfContextString = "OPRFV1-\x00-ristretto255-SHA512"
+
+// ErrInvalidOPRFInput is returned when the input maps to the identity element.
+var ErrInvalidOPRFInput = errors.New("invalid OPRF input")
+
+// DeriveOPRFKey deterministically derives an OPRF private key from a 32 byte seed and info.
+func DeriveOPRFKey(seed, info []byte) (*ristretto255.Scalar, error) {
+	if len(seed) != 32 {
+		return nil, fmt.Errorf("seed must be 32 bytes, got %d", len(seed))
+	}
+	if len(info) > 0xffff {
+		return nil, fmt.Errorf("info is too long")
+	}
+
+	deriveInput := append(append(append([]byte{}, seed...), i2osp2(len(info))...), info...)
+	zero := ristretto255.NewScalar()
+	for counter := 0; counter < 256; counter++ {
+		skS := hashToScalar(append(append([]byte{}, deriveInput...), byte(counter)), "DeriveKeyPair"+oprfContextString)
+		if skS.Equal(zero) != 1 {
+			return skS, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to derive OPRF key")
+}
+
+// OPRFBlind blinds the input, the blind is kept by the client to finalize the evaluated element.
+func OPRFBlind(input []byte) (*ristretto255.Scalar, *ristretto255.Element, error) {
+	blind, err := RandomScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blindedElement, err := oprfBlind(input, blind)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blind, blindedElement, nil
+}
+
+func oprfBlind(input []byte, blind *ristretto255.Scalar) (*ristretto255.Element, error) {
+	inputElement := hashToGroup(input)
+	if inputElement.Equal(ristretto255.NewElement().Zero()) == 1 {
+		return nil, ErrInvalidOPRFInput
+	}
+	return ristretto255.NewElement().ScalarMult(blind, inputElement), nil
+}
+
+// OPRFBlindEvaluate evaluates a blinded element with the private key of the server.
+func OPRFBlindEvaluate(privateKey *ristretto255.Scalar, blindedElement *ristretto255.Element) *ristretto255.Element {
+	return ristretto255.NewElement().ScalarMult(privateKey, blindedElement)
+}
+
+// OPRFFinalize unblinds the evaluated element and returns the 64 byte output of the OPRF.
+func OPRFFinalize(input []byte, blind *ristretto255.Scalar, evaluatedElement *ristretto255.Element) []byte {
+	n := ristretto255.NewElement().ScalarMult(ristretto255.NewScalar().Invert(blind), evaluatedElement)
+	unblindedElement := n.Encode(nil)
+
+	hashInput := append(i2osp2(len(input)), input...)
+	hashInput = append(hashInput, i2osp2(len(unblindedElement))...)
+	hashInput = append(hashInput, unblindedElement...)
+	hashInput = append(hashInput, "Finalize"...)
+
+	output := sha512.Sum512(hashInput)
+	return output[:]
+}
+
+func hashToGroup(input []byte) *ristretto255.Element {
+	uniformBytes := expandMessageXMD(input, []byte("HashToGroup-"+oprfContextString), 64)
+	return ristretto255.NewElement().FromUniformBytes(uniformBytes)
+}
+
+func hashToScalar(input []byte, dst string) *ristretto255.Scalar {
+	uniformBytes := expandMessageXMD(input, []byte(dst), 64)
+	return ristretto255.NewScalar().FromUniformBytes(uniformBytes)
+}
+
+// expandMessageXMD implements expand_message_xmd of RFC 9380 with SHA-512.
+func expandMessageXMD(msg, dst []byte, length int) []byte {
+	const (
+		bInBytes = sha512.Size
+		sInBytes = sha512.BlockSize
+	)
+
+	ell := (length + bInBytes - 1) / bInBytes
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha512.New()
+	h.Write(make([]byte, sInBytes))
+	h.Write(msg)
+	h.Write(i2osp2(length))
+	h.Write([]byte{0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	uniformBytes := append([]byte{}, bi...)
+	for i := 2; i <= ell; i++ {
+		h.Reset()
+		for j := range b0 {
+			h.Write([]byte{b0[j] ^ bi[j]})
+		}
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		uniformBytes = append(uniformBytes, bi...)
+	}
+
+	return uniformBytes[:length]
+}
+
+func i2osp2(n int) []byte {
+	return binary.BigEndian.AppendUint16(nil, uint16(n))
+}
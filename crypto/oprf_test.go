@@ -0,0 +1,100 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/gtank/ristretto255"
+)
+
+// oprfVector is a test vector of RFC 9497 with a batch size of 1.
+type oprfVector struct {
+	input             string
+	blind             string
+	blindedElement    string
+	evaluationElement string
+	output            string
+}
+
+// oprfVectors are the test vectors of the OPRF mode with ristretto255-SHA512, RFC 9497 appendix A.1.1.
+var oprfVectors = struct {
+	seed    string
+	keyInfo string
+	skSm    string
+	tests   []oprfVector
+}{
+	seed:    "a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3a3",
+	keyInfo: "74657374206b6579",
+	skSm:    "5ebcea5ee37023ccb9fc2d2019f9d7737be85591ae8652ffa9ef0f4d37063b0e",
+	tests: []oprfVector{
+		{
+			input:             "00",
+			blind:             "64d37aed22a27f5191de1c1d69fadb899d8862b58eb4220029e036ec4c1f6706",
+			blindedElement:    "609a0ae68c15a3cf6903766461307e5c8bb2f95e7e6550e1ffa2dc99e412803c",
+			evaluationElement: "7ec6578ae5120958eb2db1745758ff379e77cb64fe77b0b2d8cc917ea0869c7e",
+			output:            "527759c3d9366f277d8c6020418d96bb393ba2afb20ff90df23fb7708264e2f3ab9135e3bd69955851de4b1f9fe8a0973396719b7912ba9ee8aa7d0b5e24bcf6",
+		},
+		{
+			input:             "5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a5a",
+			blind:             "64d37aed22a27f5191de1c1d69fadb899d8862b58eb4220029e036ec4c1f6706",
+			blindedElement:    "da27ef466870f5f15296299850aa088629945a17d1f5b7f5ff043f76b3c06418",
+			evaluationElement: "b4cbf5a4f1eeda5a63ce7b77c7d23f461db3fcab0dd28e4e17cecb5c90d02c25",
+			output:            "f4a74c9c592497375e796aa837e907b1a045d34306a749db9f34221f7e750cb4f2a6413a6bf6fa5e19ba6348eb673934a722a7ede2e7621306d18951e7cf2c73",
+		},
+	},
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDeriveOPRFKey(t *testing.T) {
+	key, err := DeriveOPRFKey(mustDecodeHex(t, oprfVectors.seed), mustDecodeHex(t, oprfVectors.keyInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(key.Encode(nil)); got != oprfVectors.skSm {
+		t.Fatalf("skSm = %s, want %s", got, oprfVectors.skSm)
+	}
+}
+
+func TestOPRFVectors(t *testing.T) {
+	key, err := DeriveOPRFKey(mustDecodeHex(t, oprfVectors.seed), mustDecodeHex(t, oprfVectors.keyInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range oprfVectors.tests {
+		t.Run(test.input, func(t *testing.T) {
+			input := mustDecodeHex(t, test.input)
+			blind := ristretto255.NewScalar()
+			if err := blind.Decode(mustDecodeHex(t, test.blind)); err != nil {
+				t.Fatal(err)
+			}
+
+			blindedElement, err := oprfBlind(input, blind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(blindedElement.Encode(nil)); got != test.blindedElement {
+				t.Fatalf("blinded element = %s, want %s", got, test.blindedElement)
+			}
+
+			evaluatedElement := OPRFBlindEvaluate(key, blindedElement)
+			if got := hex.EncodeToString(evaluatedElement.Encode(nil)); got != test.evaluationElement {
+				t.Fatalf("evaluation element = %s, want %s", got, test.evaluationElement)
+			}
+
+			output := OPRFFinalize(input, blind, evaluatedElement)
+			if !bytes.Equal(output, mustDecodeHex(t, test.output)) {
+				t.Fatalf("output = %x, want %s", output, test.output)
+			}
+		})
+	}
+}
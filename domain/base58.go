@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// base58Alphabet is the Bitcoin alphabet, without 0, O, I and l which are easily confused.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var bigRadix = big.NewInt(58)
+
+// encodeBase58 encodes the data in base58, every leading zero byte is encoded as a leading 1.
+func encodeBase58(data []byte) string {
+	var encoded []byte
+	n := new(big.Int).SetBytes(data)
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, bigRadix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	// the digits were appended with the least significant first
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// decodeBase58String decodes a base58 string, it fails on characters which are not in the alphabet.
+func decodeBase58String(value string) ([]byte, error) {
+	n := new(big.Int)
+	for i, c := range value {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit == -1 {
+			return nil, fmt.Errorf("invalid character %q at position %d", c, i)
+		}
+		n.Mul(n, bigRadix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	zeros := len(value) - len(strings.TrimLeft(value, base58Alphabet[:1]))
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+var (
+	// ErrWrongContentType is returned when a container holds different content than expected, e.g. a token instead of a pseudonym.
+	ErrWrongContentType = errors.New("wrong content type")
+	// ErrUnsupportedVersion is returned when a container has a version which is not supported.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	// ErrMalformed is returned when a token or pseudonym can not be parsed.
+	ErrMalformed = errors.New("malformed token or pseudonym")
+	// ErrDecryptionFailed is returned when a token or pseudonym can not be decrypted, e.g. because it has been tampered with.
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrCorruptedIdentifier is returned when the checksum of a token or pseudonym does not match, e.g. because of a typo.
+	ErrCorruptedIdentifier = errors.New("corrupted token or pseudonym")
+)
+
+// encodeContainer serializes a container to the string representation of tokens and pseudonyms, in the compact format.
+func encodeContainer(container *pb.Container) (string, error) {
+	return encodeIdentifier(container, FormatCompact)
+}
+
+// decodeContainer parses the string representation of tokens and pseudonyms, in any of the formats.
+func decodeContainer(value string) (*pb.Container, error) {
+	identifier, err := ParseIdentifier(value)
+	if err != nil {
+		return nil, err
+	}
+	return identifier.container, nil
+}
+
+// checkHeader checks that the container has a supported version and holds the expected content type.
+// The header is bound to the ciphertext as additional data, but its value must be checked to prevent
+// a container being used as another type when the same key is used.
+func checkHeader(container *pb.Container, contentType pb.ContentType) error {
+	if container.Header == nil {
+		return fmt.Errorf("%w: container has no header", ErrMalformed)
+	}
+	if _, ok := pb.Version_name[int32(container.Header.Version)]; !ok {
+		return fmt.Errorf("%w: %d", ErrUnsupportedVersion, container.Header.Version)
+	}
+	if container.Header.ContentType != contentType {
+		return fmt.Errorf("%w: expected %s, got %s", ErrWrongContentType, contentType, container.Header.ContentType)
+	}
+	return nil
+}
+
+// decryptionError wraps an error of the decryption of a token or pseudonym. A nonce of the wrong size is malformed
+// input, any other error means the ciphertext was tampered with or encrypted with another key.
+func decryptionError(err error) error {
+	if errors.Is(err, crypto.ErrInvalidNonce) {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecryptTokenHeader(t *testing.T) {
+	keyring, err := keys.NewKeyring("k1", map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenString, err := CreateToken(&pb.Token{Subject: "123456789", Audience: "ura:456"}, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := decodeContainer(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header func(header *pb.Header) *pb.Header
+		err    error
+	}{
+		{name: "valid", header: func(header *pb.Header) *pb.Header { return header }},
+		{name: "no header", header: func(*pb.Header) *pb.Header { return nil }, err: ErrMalformed},
+		{
+			name:   "unsupported version",
+			header: func(header *pb.Header) *pb.Header { header.Version = 7; return header },
+			err:    ErrUnsupportedVersion,
+		},
+		{
+			name:   "pseudonym",
+			header: func(header *pb.Header) *pb.Header { header.ContentType = pb.ContentType_PSEUDONYM; return header },
+			err:    ErrWrongContentType,
+		},
+		{
+			name:   "unknown key ID",
+			header: func(header *pb.Header) *pb.Header { header.KeyId = "k3"; return header },
+			err:    keys.ErrUnknownKey,
+		},
+		{
+			// the header is authenticated, so another key ID of the keyring fails to decrypt
+			name:   "other key ID",
+			header: func(header *pb.Header) *pb.Header { header.KeyId = "k2"; return header },
+			err:    ErrDecryptionFailed,
+		},
+		{
+			name:   "other version",
+			header: func(header *pb.Header) *pb.Header { header.Version = pb.Version_V2; return header },
+			err:    ErrDecryptionFailed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			modified := proto.Clone(container).(*pb.Container)
+			modified.Header = test.header(modified.Header)
+			modifiedString, err := encodeContainer(modified)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = DecryptToken(modifiedString, keyring)
+			if test.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/stevenvegt/pseudonyms/cose"
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+// TokenFormatCWT is a CBOR Web Token (RFC 8392), a COSE_Encrypt0 message with the claims of the token encrypted with
+// A256GCM.
+const TokenFormatCWT TokenFormat = "cwt"
+
+// PseudonymFormatCOSE is a COSE_Encrypt0 message with the pseudonym encrypted with AES-GCM-SIV.
+const PseudonymFormatCOSE PseudonymFormat = "cose"
+
+// cwtClaims are the claims of a token in a CWT.
+type cwtClaims struct {
+	Issuer     string `cbor:"1,keyasint,omitempty"`
+	Subject    string `cbor:"2,keyasint,omitempty"`
+	Audience   string `cbor:"3,keyasint,omitempty"`
+	Expiration int64  `cbor:"4,keyasint,omitempty"`
+	NotBefore  int64  `cbor:"5,keyasint,omitempty"`
+	IssuedAt   int64  `cbor:"6,keyasint,omitempty"`
+	ID         []byte `cbor:"7,keyasint,omitempty"`
+	// Scope are the names of the scopes of the token, separated by spaces, the scope claim of RFC 9200.
+	Scope     string `cbor:"9,keyasint,omitempty"`
+	SingleUse bool   `cbor:"single_use,omitempty"`
+}
+
+// cosePseudonym is the content of a pseudonym in a COSE_Encrypt0 message.
+type cosePseudonym struct {
+	Subject  string   `cbor:"sub"`
+	Audience string   `cbor:"aud"`
+	Version  int32    `cbor:"ver"`
+	Scope    pb.Scope `cbor:"scope"`
+}
+
+// EncodeCOSE returns the string representation of a COSE message, base64url without padding.
+func EncodeCOSE(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCOSE returns the COSE message of a token or pseudonym, and false when it is not a COSE message.
+func DecodeCOSE(value string) ([]byte, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || !cose.IsEncrypt0(data) {
+		return nil, false
+	}
+	return data, true
+}
+
+// coseTokenKey derives the content encryption key of CWT tokens from the token key.
+func coseTokenKey(master []byte) ([]byte, error) {
+	return crypto.DeriveKey(master, nil, []byte("token\x00cose"), 32)
+}
+
+// CreateTokenCWT encrypts the token as a CWT. A token without an ID gets a random ID.
+func CreateTokenCWT(token *pb.Token, keyring *keys.Keyring) (string, error) {
+	keyID, master := keyring.Active()
+
+	if err := ensureTokenID(token); err != nil {
+		return "", err
+	}
+
+	key, err := coseTokenKey(master)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := cose.Marshal(cwtClaims{
+		Issuer:     token.Issuer,
+		Subject:    token.Subject,
+		Audience:   token.Audience,
+		Expiration: token.Expiration,
+		NotBefore:  token.NotBefore,
+		IssuedAt:   token.IssuedAt,
+		ID:         []byte(token.Jti),
+		Scope:      scopeNames(token.Scopes),
+		SingleUse:  token.SingleUse,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	message, err := cose.NewEncrypt0(cose.AlgorithmA256GCM, []byte(keyID), nil)
+	if err != nil {
+		return "", err
+	}
+	message.CWT = true
+	aad, err := message.AdditionalData()
+	if err != nil {
+		return "", err
+	}
+
+	message.IV, message.Ciphertext, err = crypto.EncryptAESGCM(key, claims, aad)
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+
+	data, err := message.MarshalCBOR()
+	if err != nil {
+		return "", err
+	}
+	return EncodeCOSE(data), nil
+}
+
+// decryptTokenCOSE decrypts a token in a CWT, only A256GCM is accepted.
+func decryptTokenCOSE(data []byte, keyring *keys.Keyring) (*pb.Token, error) {
+	message, master, err := decodeCOSE(data, keyring)
+	if err != nil {
+		return nil, err
+	}
+	if !message.CWT || message.Algorithm != cose.AlgorithmA256GCM {
+		return nil, fmt.Errorf("%w: expected a CWT", ErrWrongContentType)
+	}
+
+	key, err := coseTokenKey(master)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := message.AdditionalData()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := crypto.DecryptAESGCM(key, message.IV, message.Ciphertext, aad)
+	if err != nil {
+		return nil, decryptionError(err)
+	}
+
+	claims := cwtClaims{}
+	if err := cose.Unmarshal(plaintext, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	token := &pb.Token{
+		Subject:    claims.Subject,
+		Issuer:     claims.Issuer,
+		Audience:   claims.Audience,
+		Expiration: claims.Expiration,
+		IssuedAt:   claims.IssuedAt,
+		NotBefore:  claims.NotBefore,
+		Jti:        string(claims.ID),
+		SingleUse:  claims.SingleUse,
+	}
+	if token.Scopes, err = parseScopeNames(claims.Scope); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// CreatePseudonymCOSE encrypts a pseudonym as a COSE_Encrypt0 message with AES-GCM-SIV, with the same key as
+// CreatePseudonym. The encryption is deterministic, so a subject has one pseudonym for an audience.
+func CreatePseudonymCOSE(ps *pb.Pseudonym, keyring *keys.Keyring) (string, error) {
+	if ps.Scope == pb.Scope_RESEARCH {
+		return "", ErrResearchScope
+	}
+
+	keyID, master := keyring.Active()
+
+	key, err := audienceKey(master, ps.Audience, ps.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := cose.Marshal(cosePseudonym{
+		Subject:  ps.Subject,
+		Audience: ps.Audience,
+		Version:  ps.Version,
+		Scope:    ps.Scope,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	message, err := cose.NewEncrypt0(cose.AlgorithmAESGCMSIV, []byte(keyID), nil)
+	if err != nil {
+		return "", err
+	}
+	aad, err := message.AdditionalData()
+	if err != nil {
+		return "", err
+	}
+
+	message.IV, message.Ciphertext, err = crypto.EncryptAESGCM_SIV(key, plaintext, aad)
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+
+	data, err := message.MarshalCBOR()
+	if err != nil {
+		return "", err
+	}
+	return EncodeCOSE(data), nil
+}
+
+// decryptPseudonymCOSE decrypts a pseudonym in a COSE_Encrypt0 message of the given audience and scope.
+func decryptPseudonymCOSE(data []byte, audience string, scope pb.Scope, keyring *keys.Keyring) (*pb.Pseudonym, error) {
+	message, master, err := decodeCOSE(data, keyring)
+	if err != nil {
+		return nil, err
+	}
+	if message.CWT || message.Algorithm != cose.AlgorithmAESGCMSIV {
+		return nil, fmt.Errorf("%w: expected a pseudonym", ErrWrongContentType)
+	}
+
+	key, err := audienceKey(master, audience, scope)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := message.AdditionalData()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := crypto.DecryptAESGCM_SIV(key, message.IV, message.Ciphertext, aad)
+	if err != nil {
+		return nil, decryptionError(err)
+	}
+
+	content := cosePseudonym{}
+	if err := cose.Unmarshal([]byte(plaintext), &content); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if content.Audience != audience || content.Scope != scope {
+		return nil, fmt.Errorf("%w: pseudonym does not belong to audience %s and scope %s", ErrDecryptionFailed, audience, scope)
+	}
+
+	return &pb.Pseudonym{
+		Subject:  content.Subject,
+		Audience: content.Audience,
+		Version:  content.Version,
+		Scope:    content.Scope,
+	}, nil
+}
+
+// decodeCOSE decodes a COSE_Encrypt0 message and returns the key with its key ID.
+func decodeCOSE(data []byte, keyring *keys.Keyring) (*cose.Encrypt0, []byte, error) {
+	message := &cose.Encrypt0{}
+	if err := message.UnmarshalCBOR(data); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	master, err := keyring.Key(string(message.KeyID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, master, nil
+}
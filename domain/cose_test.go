@@ -0,0 +1,213 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/cose"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecryptCOSEShortIV(t *testing.T) {
+	keyring := keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32))
+
+	tokenString, err := CreateTokenCWT(&pb.Token{Subject: "123456789", Audience: "ura:456"}, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pseudonym, err := CreatePseudonymCOSE(&pb.Pseudonym{Subject: "123456789", Audience: "ura:456"}, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		decrypt func(data []byte) error
+	}{
+		{
+			name:  "token",
+			value: tokenString,
+			decrypt: func(data []byte) error {
+				_, err := decryptTokenCOSE(data, keyring)
+				return err
+			},
+		},
+		{
+			name:  "pseudonym",
+			value: pseudonym,
+			decrypt: func(data []byte) error {
+				_, err := decryptPseudonymCOSE(data, "ura:456", pb.Scope_TREATMENT, keyring)
+				return err
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, ok := DecodeCOSE(test.value)
+			if !ok {
+				t.Fatal("expected a COSE message")
+			}
+			message := cose.Encrypt0{}
+			if err := message.UnmarshalCBOR(data); err != nil {
+				t.Fatal(err)
+			}
+			// the IV is in the unprotected header, so it can be changed without breaking the encoding
+			message.IV = message.IV[:3]
+			data, err := message.MarshalCBOR()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.decrypt(data); !errors.Is(err, ErrMalformed) {
+				t.Fatalf("expected %v for a 3 byte IV, got %v", ErrMalformed, err)
+			}
+		})
+	}
+}
+
+// withKeyID returns the COSE message with another key ID in its unprotected header.
+func withKeyID(t *testing.T, value string, keyID string) string {
+	t.Helper()
+	data, ok := DecodeCOSE(value)
+	if !ok {
+		t.Fatal("expected a COSE message")
+	}
+	message := cose.Encrypt0{}
+	if err := message.UnmarshalCBOR(data); err != nil {
+		t.Fatal(err)
+	}
+	message.KeyID = []byte(keyID)
+	data, err := message.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return EncodeCOSE(data)
+}
+
+func TestCWTToken(t *testing.T) {
+	oldKeyring, err := keys.NewKeyring("old", map[string][]byte{"old": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := keys.NewKeyring("new", map[string][]byte{
+		"old": bytes.Repeat([]byte{1}, 32),
+		"new": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := &pb.Token{
+		Subject:    "123456789",
+		Issuer:     "ura:123",
+		Audience:   "ura:456",
+		Expiration: 1700000300,
+		IssuedAt:   1700000000,
+		NotBefore:  1700000010,
+		Jti:        "cwt",
+		Scopes:     []pb.Scope{pb.Scope_TREATMENT, pb.Scope_RESEARCH},
+		SingleUse:  true,
+	}
+	oldToken, err := CreateTokenCWT(proto.Clone(token).(*pb.Token), oldKeyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := CreateTokenCWT(proto.Clone(token).(*pb.Token), rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pseudonym, err := CreatePseudonymCOSE(&pb.Pseudonym{Subject: "123456789", Audience: "ura:456"}, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		keyring *keys.Keyring
+		err     error
+	}{
+		{name: "round trip", token: newToken, keyring: rotated},
+		{name: "token from before the rotation", token: oldToken, keyring: rotated},
+		{name: "token from after the rotation", token: newToken, keyring: oldKeyring, err: keys.ErrUnknownKey},
+		{name: "unknown kid", token: withKeyID(t, newToken, "unknown"), keyring: rotated, err: keys.ErrUnknownKey},
+		{name: "wrong kid", token: withKeyID(t, newToken, "old"), keyring: rotated, err: ErrDecryptionFailed},
+		{name: "pseudonym", token: pseudonym, keyring: rotated, err: ErrWrongContentType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decrypted, err := DecryptToken(test.token, test.keyring)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(decrypted, token) {
+				t.Fatalf("decrypted token differs:\n got %v\nwant %v", decrypted, token)
+			}
+		})
+	}
+}
+
+func TestCOSEPseudonym(t *testing.T) {
+	keyring, err := keys.NewKeyring("new", map[string][]byte{
+		"old": bytes.Repeat([]byte{1}, 32),
+		"new": bytes.Repeat([]byte{2}, 32),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pseudonym := &pb.Pseudonym{Subject: "123456789", Audience: "ura:456", Scope: pb.Scope_TREATMENT, Version: 1}
+	pseudonymString, err := CreatePseudonymCOSE(pseudonym, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := CreatePseudonymCOSE(pseudonym, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != pseudonymString {
+		t.Fatal("expected the same pseudonym for the same subject and audience")
+	}
+	tokenString, err := CreateTokenCWT(&pb.Token{Subject: "123456789", Audience: "ura:456"}, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		pseudonym string
+		audience  string
+		err       error
+	}{
+		{name: "round trip", pseudonym: pseudonymString, audience: "ura:456"},
+		{name: "other audience", pseudonym: pseudonymString, audience: "ura:789", err: ErrDecryptionFailed},
+		{name: "unknown kid", pseudonym: withKeyID(t, pseudonymString, "unknown"), audience: "ura:456", err: keys.ErrUnknownKey},
+		{name: "wrong kid", pseudonym: withKeyID(t, pseudonymString, "old"), audience: "ura:456", err: ErrDecryptionFailed},
+		{name: "token", pseudonym: tokenString, audience: "ura:456", err: ErrWrongContentType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decrypted, err := DecryptPseudonum(test.pseudonym, test.audience, pb.Scope_TREATMENT, keyring)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(decrypted, pseudonym) {
+				t.Fatalf("decrypted pseudonym differs:\n got %v\nwant %v", decrypted, pseudonym)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format is the string representation of tokens and pseudonyms.
+type Format string
+
+const (
+	// FormatCompact is <version>.<type>.<base64url of the binary protobuf>, e.g. prs1.psd.ChQQ...
+	FormatCompact Format = "compact"
+	// FormatBase58 is <version>-<type>-<base58 of the binary protobuf and a checksum>, e.g. prs1-psd-3mJr...
+	// It only contains letters, digits and dashes, so it can be used in URLs and filenames without escaping, and the
+	// checksum detects typos.
+	FormatBase58 Format = "base58"
+	// FormatLegacy is base64 of the protobuf text format, it is parsed but never created.
+	FormatLegacy Format = "legacy"
+)
+
+// ParseFormat returns the format with the name, the compact format when the name is empty.
+// The legacy format can not be selected.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatCompact:
+		return FormatCompact, nil
+	case FormatBase58:
+		return FormatBase58, nil
+	default:
+		return "", fmt.Errorf("unknown format: %s", name)
+	}
+}
+
+// identifierVersion is the version prefix of the compact and base58 formats.
+const identifierVersion = "prs1"
+
+// checksumSize is the number of bytes of the checksum in the base58 format.
+const checksumSize = 4
+
+// identifierTypes are the prefixes of the content types in the compact and base58 formats, e.g. prs1.tok. for tokens.
+var identifierTypes = map[pb.ContentType]string{
+	pb.ContentType_TOKEN:                 "tok",
+	pb.ContentType_PSEUDONYM:             "psd",
+	pb.ContentType_POLYMORPHIC_PSEUDONYM: "pps",
+	pb.ContentType_ENCRYPTED_PSEUDONYM:   "eps",
+	pb.ContentType_RESEARCH_PSEUDONYM:    "rps",
+}
+
+// Identifier is a parsed token or pseudonym, which has not been decrypted.
+type Identifier struct {
+	// Format is the format the identifier was parsed from.
+	Format Format
+	// ContentType is the type of the token or pseudonym.
+	ContentType pb.ContentType
+	container   *pb.Container
+}
+
+// ParseIdentifier parses a token or pseudonym in any of the formats, the format is detected from the separator after
+// the version. The legacy format has no separator. A base58 identifier of which the checksum does not match returns
+// ErrCorruptedIdentifier, other identifiers which can not be parsed return ErrMalformed.
+func ParseIdentifier(value string) (*Identifier, error) {
+	var (
+		container *pb.Container
+		format    Format
+		err       error
+	)
+	switch separator := strings.IndexAny(value, ".-"); {
+	case separator == -1:
+		format = FormatLegacy
+		container, err = decodeLegacy(value)
+	case value[separator] == '.':
+		format = FormatCompact
+		container, err = decodeCompact(value)
+	default:
+		format = FormatBase58
+		container, err = decodeBase58(value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identifier{
+		Format:      format,
+		ContentType: container.Header.GetContentType(),
+		container:   container,
+	}, nil
+}
+
+// FormatIdentifier returns a token or pseudonym in another format, its content is not changed.
+// JWE, PASETO and COSE tokens and pseudonyms are not containers, they are returned unchanged.
+func FormatIdentifier(value string, format Format) (string, error) {
+	if _, ok := DecodeCOSE(value); ok || isJWE(value) || isPASETO(value) {
+		return value, nil
+	}
+
+	identifier, err := ParseIdentifier(value)
+	if err != nil {
+		return "", err
+	}
+	if identifier.Format == format {
+		return value, nil
+	}
+	return encodeIdentifier(identifier.container, format)
+}
+
+// encodeIdentifier serializes a container in the format. The serialization is deterministic, so equal pseudonyms
+// have equal representations.
+func encodeIdentifier(container *pb.Container, format Format) (string, error) {
+	contentType, ok := identifierTypes[container.Header.GetContentType()]
+	if !ok {
+		return "", fmt.Errorf("unknown content type: %s", container.Header.GetContentType())
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(container)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case FormatCompact:
+		return identifierVersion + "." + contentType + "." + base64.RawURLEncoding.EncodeToString(data), nil
+	case FormatBase58:
+		prefix := identifierVersion + "-" + contentType + "-"
+		return prefix + encodeBase58(append(data, checksum(prefix, data)...)), nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// decodeCompact parses an identifier in the compact format.
+func decodeCompact(value string) (*pb.Container, error) {
+	prefix, encoded, err := splitPrefix(value, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return unmarshalContainer(data, prefix)
+}
+
+// decodeBase58 parses an identifier in the base58 format and verifies its checksum.
+func decodeBase58(value string) (*pb.Container, error) {
+	prefix, encoded, err := splitPrefix(value, "-")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := decodeBase58String(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptedIdentifier, err)
+	}
+	if len(data) < checksumSize {
+		return nil, fmt.Errorf("%w: too short", ErrCorruptedIdentifier)
+	}
+	data, sum := data[:len(data)-checksumSize], data[len(data)-checksumSize:]
+	if !bytes.Equal(sum, checksum(value[:len(value)-len(encoded)], data)) {
+		return nil, fmt.Errorf("%w: checksum does not match", ErrCorruptedIdentifier)
+	}
+	return unmarshalContainer(data, prefix)
+}
+
+// decodeLegacy parses an identifier in the legacy format, base64 of the protobuf text format.
+func decodeLegacy(value string) (*pb.Container, error) {
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	container := pb.Container{}
+	err = prototext.Unmarshal(data, &container)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	return &container, nil
+}
+
+// splitPrefix splits an identifier in the type of its prefix and the encoded container, and checks its version.
+func splitPrefix(value string, separator string) (string, string, error) {
+	parts := strings.SplitN(value, separator, 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("%w: expected <version>%s<type>%s<data>", ErrMalformed, separator, separator)
+	}
+	if parts[0] != identifierVersion {
+		return "", "", fmt.Errorf("%w: encoding %s", ErrUnsupportedVersion, parts[0])
+	}
+	return parts[1], parts[2], nil
+}
+
+// unmarshalContainer parses the binary protobuf of a container, of which the prefix has the type.
+func unmarshalContainer(data []byte, contentType string) (*pb.Container, error) {
+	container := pb.Container{}
+	if err := proto.Unmarshal(data, &container); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	// the type in the prefix is not authenticated, so it must match the content type in the header
+	if contentType != identifierTypes[container.Header.GetContentType()] {
+		return nil, fmt.Errorf("%w: type %s does not match content type %s", ErrMalformed, contentType, container.Header.GetContentType())
+	}
+	return &container, nil
+}
+
+// checksum returns the checksum of the base58 format, which covers the prefix and the data.
+func checksum(prefix string, data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(prefix))
+	h.Write(data)
+	return h.Sum(nil)[:checksumSize]
+}
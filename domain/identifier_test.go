@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+func TestDecryptBase58Pseudonym(t *testing.T) {
+	keyring, err := keys.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// another key with the same key ID, which fails to decrypt instead of being unknown
+	otherKeyring, err := keys.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{2}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pseudonym := &pb.Pseudonym{Subject: "123456789", Audience: "ura:456", Scope: pb.Scope_TREATMENT}
+	compact, err := CreatePseudonym(pseudonym, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base58, err := FormatIdentifier(compact, FormatBase58)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(base58, "prs1-psd-") {
+		t.Fatalf("expected a base58 pseudonym, got %s", base58)
+	}
+
+	// a pseudonym of which the ciphertext is modified, with a checksum which matches
+	container, err := decodeContainer(compact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	container.Ciphertext[0] ^= 1
+	tampered, err := encodeIdentifier(container, FormatBase58)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replace returns the base58 pseudonym with the character at i replaced by the next character of the alphabet
+	replace := func(i int) string {
+		next := base58Alphabet[(strings.IndexByte(base58Alphabet, base58[i])+1)%len(base58Alphabet)]
+		return base58[:i] + string(next) + base58[i+1:]
+	}
+
+	tests := []struct {
+		name      string
+		pseudonym string
+		keyring   *keys.Keyring
+		err       error
+	}{
+		{name: "base58", pseudonym: base58},
+		{name: "typo", pseudonym: replace(len(base58) / 2), err: ErrCorruptedIdentifier},
+		{name: "typo in checksum", pseudonym: replace(len(base58) - 1), err: ErrCorruptedIdentifier},
+		{name: "character not in alphabet", pseudonym: base58[:len(base58)-1] + "0", err: ErrCorruptedIdentifier},
+		{name: "missing character", pseudonym: base58[:len(base58)-1], err: ErrCorruptedIdentifier},
+		{name: "too short", pseudonym: "prs1-psd-2", err: ErrCorruptedIdentifier},
+		{name: "other type", pseudonym: strings.Replace(base58, "-psd-", "-tok-", 1), err: ErrCorruptedIdentifier},
+		{name: "modified ciphertext", pseudonym: tampered, err: ErrDecryptionFailed},
+		{name: "other key", pseudonym: base58, keyring: otherKeyring, err: ErrDecryptionFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keyring := keyring
+			if test.keyring != nil {
+				keyring = test.keyring
+			}
+			decrypted, err := DecryptPseudonum(test.pseudonym, "ura:456", pb.Scope_TREATMENT, keyring)
+			if test.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if decrypted.Subject != pseudonym.Subject {
+					t.Fatalf("expected subject %s, got %s", pseudonym.Subject, decrypted.Subject)
+				}
+				return
+			}
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
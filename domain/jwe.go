@@ -0,0 +1,175 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+// TokenFormatJWE is a compact JWE (RFC 7516) with the claims of the token, encrypted with dir and A256GCM.
+const TokenFormatJWE TokenFormat = "jwe"
+
+// tokenClaims are the claims of a token in a JWE.
+type tokenClaims struct {
+	jwt.Claims
+	// Scope are the names of the scopes of the token, separated by spaces, e.g. TREATMENT.
+	Scope     string `json:"scope,omitempty"`
+	SingleUse bool   `json:"single_use,omitempty"`
+}
+
+// jweKey derives the content encryption key of JWE tokens from the token key, so the key of JWE tokens is
+// independent of the key of the containers.
+func jweKey(master []byte) ([]byte, error) {
+	return crypto.DeriveKey(master, nil, []byte("token\x00jwe"), 32)
+}
+
+// jweKeys derives the JWE key of every token key. The ID of a JWE key is derived from the JWE key itself, so the kid
+// of a JWE identifies the key a partner needs to decrypt it.
+func jweKeys(keyring *keys.Keyring) (*keys.Keyring, error) {
+	activeID, _ := keyring.Active()
+
+	jweKeyID := ""
+	derived := map[string][]byte{}
+	for _, id := range keyring.IDs() {
+		master, err := keyring.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jweKey(master)
+		if err != nil {
+			return nil, err
+		}
+		derived[keys.KeyID(key)] = key
+		if id == activeID {
+			jweKeyID = keys.KeyID(key)
+		}
+	}
+	return keys.NewKeyring(jweKeyID, derived)
+}
+
+// JWEKeySet returns the JWE keys of the token keys as a JWK Set, for partners which decrypt JWE tokens themselves.
+func JWEKeySet(keyring *keys.Keyring) (jose.JSONWebKeySet, error) {
+	derived, err := jweKeys(keyring)
+	if err != nil {
+		return jose.JSONWebKeySet{}, err
+	}
+
+	set := jose.JSONWebKeySet{}
+	for _, id := range derived.IDs() {
+		key, err := derived.Key(id)
+		if err != nil {
+			return jose.JSONWebKeySet{}, err
+		}
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key,
+			KeyID:     id,
+			Algorithm: string(jose.DIRECT),
+			Use:       "enc",
+		})
+	}
+	return set, nil
+}
+
+// isJWE reports whether a token is a compact JWE, which has five parts. Containers never have five parts.
+func isJWE(tokenString string) bool {
+	return strings.Count(tokenString, ".") == 4
+}
+
+// CreateTokenJWE encrypts the token as a compact JWE, with the ID of the JWE key as kid. A token without an ID gets a
+// random ID.
+func CreateTokenJWE(token *pb.Token, keyring *keys.Keyring) (string, error) {
+	derived, err := jweKeys(keyring)
+	if err != nil {
+		return "", err
+	}
+	keyID, key := derived.Active()
+
+	if err := ensureTokenID(token); err != nil {
+		return "", err
+	}
+
+	encrypter, err := jose.NewEncrypter(jose.A256GCM, jose.Recipient{
+		Algorithm: jose.DIRECT,
+		Key:       key,
+		KeyID:     keyID,
+	}, (&jose.EncrypterOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+
+	claims := tokenClaims{
+		Claims: jwt.Claims{
+			Subject:  token.Subject,
+			Issuer:   token.Issuer,
+			Audience: jwt.Audience{token.Audience},
+			Expiry:   jwt.NewNumericDate(time.Unix(token.Expiration, 0)),
+			IssuedAt: jwt.NewNumericDate(time.Unix(token.IssuedAt, 0)),
+			ID:       token.Jti,
+		},
+		Scope:     scopeNames(token.Scopes),
+		SingleUse: token.SingleUse,
+	}
+	if token.NotBefore != 0 {
+		claims.NotBefore = jwt.NewNumericDate(time.Unix(token.NotBefore, 0))
+	}
+
+	tokenString, err := jwt.Encrypted(encrypter).Claims(claims).Serialize()
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+	return tokenString, nil
+}
+
+// decryptTokenJWE decrypts a token in a compact JWE, only dir with A256GCM is accepted.
+func decryptTokenJWE(tokenString string, keyring *keys.Keyring) (*pb.Token, error) {
+	encrypted, err := jwt.ParseEncrypted(tokenString, []jose.KeyAlgorithm{jose.DIRECT}, []jose.ContentEncryption{jose.A256GCM})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	derived, err := jweKeys(keyring)
+	if err != nil {
+		return nil, err
+	}
+	key, err := derived.Key(encrypted.Headers[0].KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := tokenClaims{}
+	if err := encrypted.Claims(key, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	if len(claims.Audience) != 1 {
+		return nil, fmt.Errorf("%w: token must have one audience", ErrMalformed)
+	}
+
+	token := &pb.Token{
+		Subject:    claims.Subject,
+		Issuer:     claims.Issuer,
+		Audience:   claims.Audience[0],
+		Expiration: unixTime(claims.Expiry),
+		IssuedAt:   unixTime(claims.IssuedAt),
+		NotBefore:  unixTime(claims.NotBefore),
+		Jti:        claims.ID,
+		SingleUse:  claims.SingleUse,
+	}
+	if token.Scopes, err = parseScopeNames(claims.Scope); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// unixTime returns the time in seconds since the epoch, 0 when the time is absent.
+func unixTime(date *jwt.NumericDate) int64 {
+	if date == nil {
+		return 0
+	}
+	return date.Time().Unix()
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// encryptJWE encrypts the claims as a compact JWE with the key and kid.
+func encryptJWE(t *testing.T, enc jose.ContentEncryption, key []byte, keyID string, claims any) string {
+	t.Helper()
+	encrypter, err := jose.NewEncrypter(enc, jose.Recipient{Algorithm: jose.DIRECT, Key: key, KeyID: keyID}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenString, err := jwt.Encrypted(encrypter).Claims(claims).Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString
+}
+
+func TestJWEToken(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	oldKeyring, err := keys.NewKeyring("old", map[string][]byte{"old": oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := keys.NewKeyring("new", map[string][]byte{"old": oldKey, "new": newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeyring := keys.NewSingleKeyring(bytes.Repeat([]byte{3}, 32))
+
+	token := &pb.Token{
+		Subject:    "123456789",
+		Issuer:     "ura:123",
+		Audience:   "ura:456",
+		Expiration: 1700000300,
+		IssuedAt:   1700000000,
+		NotBefore:  1700000010,
+		Scopes:     []pb.Scope{pb.Scope_TREATMENT, pb.Scope_RESEARCH},
+		SingleUse:  true,
+	}
+	create := func(keyring *keys.Keyring) string {
+		t.Helper()
+		tokenString, err := CreateTokenJWE(proto.Clone(token).(*pb.Token), keyring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tokenString
+	}
+	oldToken := create(oldKeyring)
+	newToken := create(rotated)
+
+	// the kid of a JWE key which is in the keyring, with a key which is not
+	derived, err := jweKeys(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeKeyID, _ := derived.Active()
+	claims := tokenClaims{Claims: jwt.Claims{Subject: "123456789", Audience: jwt.Audience{"ura:456"}}}
+	wrongKey := encryptJWE(t, jose.A256GCM, bytes.Repeat([]byte{4}, 32), activeKeyID, claims)
+	otherEncryption := encryptJWE(t, jose.A128GCM, bytes.Repeat([]byte{4}, 16), activeKeyID, claims)
+
+	tests := []struct {
+		name    string
+		token   string
+		keyring *keys.Keyring
+		err     error
+	}{
+		{name: "round trip", token: newToken, keyring: rotated},
+		{name: "token from before the rotation", token: oldToken, keyring: rotated},
+		{name: "token from after the rotation", token: newToken, keyring: oldKeyring, err: keys.ErrUnknownKey},
+		{name: "kid of another keyring", token: oldToken, keyring: otherKeyring, err: keys.ErrUnknownKey},
+		{name: "wrong key for the kid", token: wrongKey, keyring: rotated, err: ErrDecryptionFailed},
+		{name: "other content encryption", token: otherEncryption, keyring: rotated, err: ErrMalformed},
+		{name: "not a JWE", token: "a.b.c.d.e", keyring: rotated, err: ErrMalformed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decrypted, err := DecryptToken(test.token, test.keyring)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("expected %v, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decrypted.Jti == "" {
+				t.Fatal("expected the token to get an ID")
+			}
+			decrypted.Jti = ""
+			if !proto.Equal(decrypted, token) {
+				t.Fatalf("decrypted token differs:\n got %v\nwant %v", decrypted, token)
+			}
+		})
+	}
+
+	// the key set holds the JWE key of every token key, by the kid of the tokens
+	set, err := JWEKeySet(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 || len(set.Key(activeKeyID)) != 1 {
+		t.Fatalf("expected both keys in the key set, got %+v", set)
+	}
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/gtank/ristretto255"
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+// oprfKey derives the OPRF key of an audience and scope from the master key.
+func oprfKey(master []byte, audience string, scope pb.Scope) (*ristretto255.Scalar, error) {
+	seed, err := crypto.DeriveKey(master, nil, []byte("oprf\x00seed"), 32)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DeriveOPRFKey(seed, fmt.Appendf(nil, "%s\x00%s", audience, scope))
+}
+
+// EvaluateOPRF evaluates an element blinded by a client with the OPRF key of the audience and scope.
+// The client unblinds the result to get its pseudonym, the service never sees the identifier of the subject.
+// The element is evaluated with the key with the key ID, or with the active key when the key ID is empty, and the ID
+// of the key is returned. The pseudonym changes with the key, so a client keeps the key ID with its pseudonyms and
+// evaluates with the same key ID to find them after a rotation.
+func EvaluateOPRF(blindedElement []byte, keyID string, audience string, scope pb.Scope, keyring *keys.Keyring) ([]byte, string, error) {
+	element := ristretto255.NewElement()
+	if err := element.Decode(blindedElement); err != nil {
+		return nil, "", fmt.Errorf("%w: invalid blinded element: %v", ErrMalformed, err)
+	}
+	// RFC 9497 requires the server to reject the identity element, the evaluation of which reveals nothing but is the
+	// same for every key
+	if element.Equal(ristretto255.NewElement().Zero()) == 1 {
+		return nil, "", fmt.Errorf("%w: blinded element is the identity element", ErrMalformed)
+	}
+
+	var (
+		master []byte
+		err    error
+	)
+	if keyID == "" {
+		keyID, master = keyring.Active()
+	} else if master, err = keyring.Key(keyID); err != nil {
+		return nil, "", err
+	}
+	key, err := oprfKey(master, audience, scope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return crypto.OPRFBlindEvaluate(key, element).Encode(nil), keyID, nil
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/gtank/ristretto255"
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+func TestEvaluateOPRFKeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	oldKeyring, err := keys.NewKeyring("old", map[string][]byte{"old": oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := keys.NewKeyring("new", map[string][]byte{"old": oldKey, "new": newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pseudonym := func(keyring *keys.Keyring, keyID string) (string, []byte) {
+		t.Helper()
+		input := []byte("123456789")
+		blind, blindedElement, err := crypto.OPRFBlind(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		evaluated, usedKeyID, err := EvaluateOPRF(blindedElement.Encode(nil), keyID, "ura:456", pb.Scope_TREATMENT, keyring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		element := ristretto255.NewElement()
+		if err := element.Decode(evaluated); err != nil {
+			t.Fatal(err)
+		}
+		return usedKeyID, crypto.OPRFFinalize(input, blind, element)
+	}
+
+	keyID, before := pseudonym(oldKeyring, "")
+	if keyID != "old" {
+		t.Fatalf("expected the active key old, got %s", keyID)
+	}
+
+	// after the rotation the pseudonym changes, which the client sees in the key ID
+	keyID, after := pseudonym(rotated, "")
+	if keyID != "new" || bytes.Equal(before, after) {
+		t.Fatalf("expected another pseudonym with key new, got key %s", keyID)
+	}
+
+	// with the key ID of the earlier pseudonym the client gets the same pseudonym
+	keyID, again := pseudonym(rotated, "old")
+	if keyID != "old" || !bytes.Equal(before, again) {
+		t.Fatalf("expected the same pseudonym with key old, got key %s", keyID)
+	}
+
+	if _, _, err := EvaluateOPRF(ristretto255.NewElement().Base().Encode(nil), "unknown", "ura:456", pb.Scope_TREATMENT, rotated); !errors.Is(err, keys.ErrUnknownKey) {
+		t.Fatalf("expected %v, got %v", keys.ErrUnknownKey, err)
+	}
+}
+
+func TestEvaluateOPRFInvalidElement(t *testing.T) {
+	keyring := keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32))
+
+	tests := []struct {
+		name    string
+		element []byte
+	}{
+		{name: "identity", element: ristretto255.NewElement().Zero().Encode(nil)},
+		{name: "not an element", element: bytes.Repeat([]byte{0xff}, 32)},
+		{name: "too short", element: []byte{1, 2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := EvaluateOPRF(test.element, "", "ura:456", pb.Scope_TREATMENT, keyring); !errors.Is(err, ErrMalformed) {
+				t.Fatalf("expected %v, got %v", ErrMalformed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"google.golang.org/protobuf/proto"
+)
+
+// TokenFormatPASETO is a PASETO v4.local token, encrypted with XChaCha20 and authenticated with BLAKE2b-MAC.
+const TokenFormatPASETO TokenFormat = "paseto"
+
+// pasetoHeader is the header of PASETO v4.local tokens.
+const pasetoHeader = "v4.local."
+
+const (
+	pasetoNonceSize = 32
+	pasetoTagSize   = 32
+)
+
+// pasetoClaims are the claims of a token in a PASETO, the times are in RFC 3339 format.
+type pasetoClaims struct {
+	Issuer     string `json:"iss,omitempty"`
+	Subject    string `json:"sub,omitempty"`
+	Audience   string `json:"aud,omitempty"`
+	Expiration string `json:"exp,omitempty"`
+	NotBefore  string `json:"nbf,omitempty"`
+	IssuedAt   string `json:"iat,omitempty"`
+	ID         string `json:"jti,omitempty"`
+	// Scope are the names of the scopes of the token, separated by spaces, e.g. TREATMENT.
+	Scope     string `json:"scope,omitempty"`
+	SingleUse bool   `json:"single_use,omitempty"`
+}
+
+// pasetoFooter is the footer of a PASETO, which is authenticated but not encrypted.
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// pasetoKey derives the key of PASETO tokens from the token key.
+func pasetoKey(master []byte) ([]byte, error) {
+	return crypto.DeriveKey(master, nil, []byte("token\x00paseto"), 32)
+}
+
+// isPASETO reports whether a token is a PASETO v4.local token.
+func isPASETO(tokenString string) bool {
+	return strings.HasPrefix(tokenString, pasetoHeader)
+}
+
+// pasetoAssertion returns the implicit assertion of a PASETO, the header of the token container. It is authenticated
+// but not part of the token, so a PASETO is bound to the version, content type and key of the service.
+func pasetoAssertion(keyID string) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(&pb.Header{
+		Version:     pb.Version_V1,
+		ContentType: pb.ContentType_TOKEN,
+		KeyId:       keyID,
+	})
+}
+
+// CreateTokenPASETO encrypts the token as a PASETO v4.local token, with the ID of the key in the footer.
+// A token without an ID gets a random ID.
+func CreateTokenPASETO(token *pb.Token, keyring *keys.Keyring) (string, error) {
+	keyID, master := keyring.Active()
+
+	if err := ensureTokenID(token); err != nil {
+		return "", err
+	}
+
+	key, err := pasetoKey(master)
+	if err != nil {
+		return "", err
+	}
+
+	claims := pasetoClaims{
+		Issuer:     token.Issuer,
+		Subject:    token.Subject,
+		Audience:   token.Audience,
+		Expiration: pasetoTime(token.Expiration),
+		NotBefore:  pasetoTime(token.NotBefore),
+		IssuedAt:   pasetoTime(token.IssuedAt),
+		ID:         token.Jti,
+		Scope:      scopeNames(token.Scopes),
+		SingleUse:  token.SingleUse,
+	}
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	footer, err := json.Marshal(pasetoFooter{KeyID: keyID})
+	if err != nil {
+		return "", err
+	}
+	assertion, err := pasetoAssertion(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, pasetoNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+	return encryptPASETO(key, nonce, message, footer, assertion)
+}
+
+// decryptTokenPASETO decrypts a PASETO v4.local token, the key is found with the ID in the footer.
+func decryptTokenPASETO(tokenString string, keyring *keys.Keyring) (*pb.Token, error) {
+	parts := strings.Split(strings.TrimPrefix(tokenString, pasetoHeader), ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%w: PASETO has no footer", ErrMalformed)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	footer, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	// the footer is authenticated, but it has to be parsed before to find the key
+	keyFooter := pasetoFooter{}
+	if err := json.Unmarshal(footer, &keyFooter); err != nil {
+		return nil, fmt.Errorf("%w: footer: %v", ErrMalformed, err)
+	}
+	master, err := keyring.Key(keyFooter.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := pasetoKey(master)
+	if err != nil {
+		return nil, err
+	}
+	assertion, err := pasetoAssertion(keyFooter.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := decryptPASETO(key, payload, footer, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := pasetoClaims{}
+	decoder := json.NewDecoder(bytes.NewReader(message))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	token := &pb.Token{
+		Subject:   claims.Subject,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		Jti:       claims.ID,
+		SingleUse: claims.SingleUse,
+	}
+	for field, value := range map[*int64]string{
+		&token.Expiration: claims.Expiration,
+		&token.NotBefore:  claims.NotBefore,
+		&token.IssuedAt:   claims.IssuedAt,
+	} {
+		if *field, err = parsePasetoTime(value); err != nil {
+			return nil, err
+		}
+	}
+	if token.Scopes, err = parseScopeNames(claims.Scope); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// encryptPASETO encrypts the message as a PASETO v4.local token with the nonce, the footer is left out when empty.
+func encryptPASETO(key, nonce, message, footer, assertion []byte) (string, error) {
+	encryptionKey, counterNonce, authenticationKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	tag, err := pasetoTag(authenticationKey, nonce, ciphertext, footer, assertion)
+	if err != nil {
+		return "", err
+	}
+
+	payload := append(append(slices.Clone(nonce), ciphertext...), tag...)
+	tokenString := pasetoHeader + base64.RawURLEncoding.EncodeToString(payload)
+	if len(footer) > 0 {
+		tokenString += "." + base64.RawURLEncoding.EncodeToString(footer)
+	}
+	return tokenString, nil
+}
+
+// decryptPASETO authenticates and decrypts the payload of a PASETO v4.local token, the nonce, ciphertext and tag.
+func decryptPASETO(key, payload, footer, assertion []byte) ([]byte, error) {
+	if len(payload) < pasetoNonceSize+pasetoTagSize {
+		return nil, fmt.Errorf("%w: PASETO is too short", ErrMalformed)
+	}
+	nonce := payload[:pasetoNonceSize]
+	ciphertext := payload[pasetoNonceSize : len(payload)-pasetoTagSize]
+	tag := payload[len(payload)-pasetoTagSize:]
+
+	encryptionKey, counterNonce, authenticationKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	expectedTag, err := pasetoTag(authenticationKey, nonce, ciphertext, footer, assertion)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, expectedTag) != 1 {
+		return nil, fmt.Errorf("%w: PASETO authentication tag does not match", ErrDecryptionFailed)
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+	return message, nil
+}
+
+// pasetoSplitKey derives the encryption key, the XChaCha20 nonce and the authentication key from the key and the
+// nonce of the token.
+func pasetoSplitKey(key []byte, nonce []byte) ([]byte, []byte, []byte, error) {
+	encryption, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	encryption.Write([]byte("paseto-encryption-key"))
+	encryption.Write(nonce)
+	tmp := encryption.Sum(nil)
+
+	authentication, err := blake2b.New(32, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	authentication.Write([]byte("paseto-auth-key-for-aead"))
+	authentication.Write(nonce)
+
+	return tmp[:32], tmp[32:], authentication.Sum(nil), nil
+}
+
+// pasetoTag computes the BLAKE2b-MAC of the pre-authentication encoding of the token.
+func pasetoTag(authenticationKey []byte, nonce, ciphertext, footer, assertion []byte) ([]byte, error) {
+	mac, err := blake2b.New(pasetoTagSize, authenticationKey)
+	if err != nil {
+		return nil, err
+	}
+	mac.Write(preAuthenticationEncoding([]byte(pasetoHeader), nonce, ciphertext, footer, assertion))
+	return mac.Sum(nil), nil
+}
+
+// preAuthenticationEncoding is PAE of the PASETO specification, which encodes the number and lengths of the pieces,
+// so different pieces never have the same encoding.
+func preAuthenticationEncoding(pieces ...[]byte) []byte {
+	encoded := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces))&^(1<<63))
+	for _, piece := range pieces {
+		encoded = binary.LittleEndian.AppendUint64(encoded, uint64(len(piece))&^(1<<63))
+		encoded = append(encoded, piece...)
+	}
+	return encoded
+}
+
+// pasetoTime formats a time in seconds since the epoch in RFC 3339, empty when the time is absent.
+func pasetoTime(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
+
+// parsePasetoTime parses a time in RFC 3339 to seconds since the epoch, 0 when the time is absent.
+func parsePasetoTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return t.Unix(), nil
+}
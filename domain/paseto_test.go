@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// pasetoVector is test vector 4-E-1 of the PASETO test vectors, a v4.local token without footer and implicit
+// assertion.
+var pasetoVector = struct {
+	key     string
+	nonce   string
+	payload string
+	token   string
+}{
+	key:     "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f",
+	nonce:   "0000000000000000000000000000000000000000000000000000000000000000",
+	payload: `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`,
+	token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+}
+
+func TestPASETOVector(t *testing.T) {
+	key, err := hex.DecodeString(pasetoVector.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, err := hex.DecodeString(pasetoVector.nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenString, err := encryptPASETO(key, nonce, []byte(pasetoVector.payload), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenString != pasetoVector.token {
+		t.Fatalf("token:\n got %s\nwant %s", tokenString, pasetoVector.token)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(pasetoVector.token, pasetoHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := decryptPASETO(key, payload, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(message) != pasetoVector.payload {
+		t.Fatalf("payload: got %s, want %s", message, pasetoVector.payload)
+	}
+
+	// the footer and the implicit assertion are authenticated
+	if _, err := decryptPASETO(key, payload, []byte("footer"), nil); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected %v for another footer, got %v", ErrDecryptionFailed, err)
+	}
+	if _, err := decryptPASETO(key, payload, nil, []byte("assertion")); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected %v for another implicit assertion, got %v", ErrDecryptionFailed, err)
+	}
+}
+
+func TestPASETOToken(t *testing.T) {
+	keyring, err := keys.NewKeyring("paseto", map[string][]byte{"paseto": bytes.Repeat([]byte{1}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := &pb.Token{
+		Subject:    "123456789",
+		Issuer:     "ura:123",
+		Audience:   "ura:456",
+		Expiration: 1700000300,
+		IssuedAt:   1700000000,
+		Scopes:     []pb.Scope{pb.Scope_TREATMENT, pb.Scope_RESEARCH},
+		SingleUse:  true,
+	}
+
+	tokenString, err := CreateTokenPASETO(token, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Jti == "" {
+		t.Fatal("expected the token to get an ID")
+	}
+	decrypted, err := DecryptToken(tokenString, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(decrypted, token) {
+		t.Fatalf("decrypted token differs:\n got %v\nwant %v", decrypted, token)
+	}
+
+	// a token does not decrypt with another key with the same ID
+	other, err := keys.NewKeyring("paseto", map[string][]byte{"paseto": bytes.Repeat([]byte{2}, 32)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptToken(tokenString, other); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("expected %v for another key, got %v", ErrDecryptionFailed, err)
+	}
+}
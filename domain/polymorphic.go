@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gtank/ristretto255"
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Polymorphic pseudonyms follow the polymorphic encryption and pseudonymisation (PEP) model.
+// A polymorphic pseudonym is an ElGamal encryption of the hashed subject under the public key of the service.
+// It is transformed into an encrypted pseudonym for an organisation by reshuffling and re-keying, without ever
+// decrypting it. The organisation decrypts the encrypted pseudonym with its own key to get its pseudonym of the subject.
+// All keys are derived from the pseudonym master key.
+
+// pepPrivateKey derives the private key of the polymorphic pseudonyms from the master key.
+func pepPrivateKey(master []byte) (*ristretto255.Scalar, error) {
+	return crypto.DeriveScalar(master, []byte("pep\x00private-key"))
+}
+
+// pepFactors derives the reshuffle and rekey factors of an audience and scope from the master key.
+func pepFactors(master []byte, audience string, scope pb.Scope) (*ristretto255.Scalar, *ristretto255.Scalar, error) {
+	reshuffle, err := crypto.DeriveScalar(master, fmt.Appendf(nil, "pep\x00reshuffle\x00%s\x00%s", audience, scope))
+	if err != nil {
+		return nil, nil, err
+	}
+	rekey, err := crypto.DeriveScalar(master, fmt.Appendf(nil, "pep\x00rekey\x00%s\x00%s", audience, scope))
+	if err != nil {
+		return nil, nil, err
+	}
+	return reshuffle, rekey, nil
+}
+
+// CreatePolymorphicPseudonym creates a polymorphic pseudonym of the subject.
+// Every call returns a different polymorphic pseudonym, they can not be linked to each other.
+func CreatePolymorphicPseudonym(subject string, keyring *keys.Keyring) (string, error) {
+	keyID, master := keyring.Active()
+
+	privateKey, err := pepPrivateKey(master)
+	if err != nil {
+		return "", err
+	}
+
+	eg, err := crypto.EncryptElGamal(crypto.HashToElement([]byte(subject)), crypto.PublicKey(privateKey))
+	if err != nil {
+		return "", fmt.Errorf("encryption failed: %v", err)
+	}
+
+	return encodeElGamal(eg, pb.ContentType_POLYMORPHIC_PSEUDONYM, keyID)
+}
+
+// TransformPolymorphicPseudonym transforms a polymorphic pseudonym into an encrypted pseudonym for the audience and scope.
+// The subject is never decrypted during the transformation.
+func TransformPolymorphicPseudonym(polymorphicPseudonym string, audience string, scope pb.Scope, keyring *keys.Keyring) (string, error) {
+	eg, keyID, err := decodeElGamal(polymorphicPseudonym, pb.ContentType_POLYMORPHIC_PSEUDONYM)
+	if err != nil {
+		return "", err
+	}
+
+	master, err := keyring.Key(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	privateKey, err := pepPrivateKey(master)
+	if err != nil {
+		return "", err
+	}
+	if crypto.PublicKey(privateKey).Equal(eg.Y) != 1 {
+		return "", fmt.Errorf("%w: polymorphic pseudonym is not encrypted for this service", ErrDecryptionFailed)
+	}
+
+	reshuffle, rekey, err := pepFactors(master, audience, scope)
+	if err != nil {
+		return "", err
+	}
+
+	eg, err = crypto.Rerandomize(crypto.Rekey(crypto.Reshuffle(eg, reshuffle), rekey))
+	if err != nil {
+		return "", err
+	}
+
+	return encodeElGamal(eg, pb.ContentType_ENCRYPTED_PSEUDONYM, keyID)
+}
+
+// OrganisationKeys are the private keys with which an organisation decrypts its encrypted pseudonyms, by the ID of the
+// pseudonym key they are derived from. In JSON the keys are base64url encoded scalars.
+type OrganisationKeys map[string]*ristretto255.Scalar
+
+// MarshalJSON encodes the keys as an object of base64url encoded scalars by their key ID.
+func (k OrganisationKeys) MarshalJSON() ([]byte, error) {
+	encoded := make(map[string]string, len(k))
+	for id, key := range k {
+		encoded[id] = base64.RawURLEncoding.EncodeToString(key.Encode(nil))
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes an object of base64url encoded scalars by their key ID.
+func (k *OrganisationKeys) UnmarshalJSON(data []byte) error {
+	encoded := map[string]string{}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	organisationKeys := make(OrganisationKeys, len(encoded))
+	for id, value := range encoded {
+		scalar, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("key %s: %v", id, err)
+		}
+		organisationKeys[id] = ristretto255.NewScalar()
+		if err := organisationKeys[id].Decode(scalar); err != nil {
+			return fmt.Errorf("key %s: %v", id, err)
+		}
+	}
+	*k = organisationKeys
+	return nil
+}
+
+// OrganisationPseudonymKey derives the private key with which the audience decrypts the encrypted pseudonyms which are
+// transformed with the pseudonym key with the given ID. The service itself never decrypts encrypted pseudonyms.
+func OrganisationPseudonymKey(audience string, scope pb.Scope, keyID string, keyring *keys.Keyring) (*ristretto255.Scalar, error) {
+	master, err := keyring.Key(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := pepPrivateKey(master)
+	if err != nil {
+		return nil, err
+	}
+
+	_, rekey, err := pepFactors(master, audience, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	return ristretto255.NewScalar().Multiply(rekey, privateKey), nil
+}
+
+// OrganisationPseudonymKeys derives the private keys of the audience for every key in the keyring, so it can decrypt
+// encrypted pseudonyms from before and after a key rotation. The keys are handed out to the organisation after every
+// rotation.
+func OrganisationPseudonymKeys(audience string, scope pb.Scope, keyring *keys.Keyring) (OrganisationKeys, error) {
+	organisationKeys := OrganisationKeys{}
+	for _, keyID := range keyring.IDs() {
+		key, err := OrganisationPseudonymKey(audience, scope, keyID, keyring)
+		if err != nil {
+			return nil, err
+		}
+		organisationKeys[keyID] = key
+	}
+	return organisationKeys, nil
+}
+
+// DecryptEncryptedPseudonym decrypts an encrypted pseudonym with the key of the organisation for the key ID in its
+// header. The result is the pseudonym of the subject for this organisation. It is stable per pseudonym key: the
+// reshuffle factor is derived from the pseudonym key, so the subject gets another pseudonym after a key rotation.
+func DecryptEncryptedPseudonym(encryptedPseudonym string, organisationKeys OrganisationKeys) (string, error) {
+	eg, keyID, err := decodeElGamal(encryptedPseudonym, pb.ContentType_ENCRYPTED_PSEUDONYM)
+	if err != nil {
+		return "", err
+	}
+
+	organisationKey, ok := organisationKeys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", keys.ErrUnknownKey, keyID)
+	}
+
+	pseudonym, err := crypto.DecryptElGamal(eg, organisationKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrDecryptionFailed, err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(pseudonym.Encode(nil)), nil
+}
+
+func encodeElGamal(eg *crypto.ElGamal, contentType pb.ContentType, keyID string) (string, error) {
+	ciphertext, err := proto.Marshal(&pb.ElGamalCiphertext{
+		B: eg.B.Encode(nil),
+		C: eg.C.Encode(nil),
+		Y: eg.Y.Encode(nil),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	container := pb.Container{
+		Header: &pb.Header{
+			Version:     pb.Version_V1,
+			ContentType: contentType,
+			KeyId:       keyID,
+		},
+		Ciphertext: ciphertext,
+	}
+
+	return encodeContainer(&container)
+}
+
+func decodeElGamal(value string, contentType pb.ContentType) (*crypto.ElGamal, string, error) {
+	container, err := decodeContainer(value)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkHeader(container, contentType); err != nil {
+		return nil, "", err
+	}
+
+	ciphertext := pb.ElGamalCiphertext{}
+	if err := proto.Unmarshal(container.Ciphertext, &ciphertext); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	eg := &crypto.ElGamal{
+		B: ristretto255.NewElement(),
+		C: ristretto255.NewElement(),
+		Y: ristretto255.NewElement(),
+	}
+	if err := eg.B.Decode(ciphertext.B); err != nil {
+		return nil, "", fmt.Errorf("%w: invalid ciphertext: %v", ErrMalformed, err)
+	}
+	if err := eg.C.Decode(ciphertext.C); err != nil {
+		return nil, "", fmt.Errorf("%w: invalid ciphertext: %v", ErrMalformed, err)
+	}
+	if err := eg.Y.Decode(ciphertext.Y); err != nil {
+		return nil, "", fmt.Errorf("%w: invalid ciphertext: %v", ErrMalformed, err)
+	}
+
+	return eg, container.Header.GetKeyId(), nil
+}
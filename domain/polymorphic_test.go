@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+func TestPolymorphicPseudonym(t *testing.T) {
+	keyring := keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32))
+	organisationKeys, err := OrganisationPseudonymKeys("ura:456", pb.Scope_TREATMENT, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every encrypted pseudonym of a subject decrypts to the same pseudonym of the organisation
+	var pseudonyms []string
+	for range 2 {
+		polymorphic, err := CreatePolymorphicPseudonym("123456789", keyring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encrypted, err := TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, keyring)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pseudonym, err := DecryptEncryptedPseudonym(encrypted, organisationKeys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pseudonyms = append(pseudonyms, pseudonym)
+	}
+	if pseudonyms[0] != pseudonyms[1] {
+		t.Fatalf("pseudonyms of the same subject differ: %s and %s", pseudonyms[0], pseudonyms[1])
+	}
+
+	// another organisation can not decrypt the encrypted pseudonym
+	otherKeys, err := OrganisationPseudonymKeys("ura:555", pb.Scope_TREATMENT, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	polymorphic, _ := CreatePolymorphicPseudonym("123456789", keyring)
+	encrypted, _ := TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, keyring)
+	if _, err := DecryptEncryptedPseudonym(encrypted, otherKeys); err == nil {
+		t.Fatal("expected an error for the keys of another organisation")
+	}
+}
+
+func TestPolymorphicPseudonymKeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	oldKeyring, err := keys.NewKeyring("old", map[string][]byte{"old": oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	polymorphic, err := CreatePolymorphicPseudonym("123456789", oldKeyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := keys.NewKeyring("new", map[string][]byte{"old": oldKey, "new": newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	organisationKeys, err := OrganisationPseudonymKeys("ura:456", pb.Scope_TREATMENT, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(organisationKeys) != 2 {
+		t.Fatalf("expected a key for both pseudonym keys, got %d", len(organisationKeys))
+	}
+
+	// a polymorphic pseudonym from before the rotation is transformed with the old key
+	encrypted, err := TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := DecryptEncryptedPseudonym(encrypted, organisationKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pseudonym is stable for the old key
+	encrypted, err = TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := DecryptEncryptedPseudonym(encrypted, organisationKeys); err != nil || again != before {
+		t.Fatalf("expected the same pseudonym for the old key, got %v", err)
+	}
+
+	// a polymorphic pseudonym from after the rotation is transformed with the new key
+	polymorphic, err = CreatePolymorphicPseudonym("123456789", rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err = TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := DecryptEncryptedPseudonym(encrypted, organisationKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the reshuffle factor is derived from the pseudonym key, so the subject has another pseudonym after the rotation
+	if after == before {
+		t.Fatal("expected another pseudonym after the rotation")
+	}
+
+	// the keys from before the rotation can not decrypt it
+	oldOrganisationKeys, err := OrganisationPseudonymKeys("ura:456", pb.Scope_TREATMENT, oldKeyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptEncryptedPseudonym(encrypted, oldOrganisationKeys); err == nil {
+		t.Fatal("expected an error for the keys from before the rotation")
+	}
+}
+
+func TestOrganisationKeysJSON(t *testing.T) {
+	keyring := keys.NewSingleKeyring(bytes.Repeat([]byte{1}, 32))
+	organisationKeys, err := OrganisationPseudonymKeys("ura:456", pb.Scope_TREATMENT, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(organisationKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := OrganisationKeys{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	polymorphic, _ := CreatePolymorphicPseudonym("123456789", keyring)
+	encrypted, _ := TransformPolymorphicPseudonym(polymorphic, "ura:456", pb.Scope_TREATMENT, keyring)
+	if _, err := DecryptEncryptedPseudonym(encrypted, decoded); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,20 +1,56 @@
 package domain
 
 import (
-	"encoding/base64"
 	"fmt"
-	"log"
 
 	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
 	pb "github.com/stevenvegt/pseudonyms/proto"
-	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 )
 
-func CreatePseudonym(ps *pb.Pseudonym, key []byte) (string, error) {
+// PseudonymFormat is the encoding of organisation pseudonyms.
+type PseudonymFormat string
+
+// PseudonymFormatContainer is the encrypted protobuf container, in the format of the receiver.
+const PseudonymFormatContainer PseudonymFormat = "container"
+
+// ParsePseudonymFormat returns the pseudonym format with the name, the container format when the name is empty.
+func ParsePseudonymFormat(name string) (PseudonymFormat, error) {
+	switch PseudonymFormat(name) {
+	case "", PseudonymFormatContainer:
+		return PseudonymFormatContainer, nil
+	case PseudonymFormatCOSE:
+		return PseudonymFormatCOSE, nil
+	default:
+		return "", fmt.Errorf("unknown pseudonym format: %s", name)
+	}
+}
+
+// audienceKey derives the key for an audience and scope from a master key using HKDF.
+// Pseudonyms of different audiences and scopes are encrypted with independent keys,
+// so a compromised audience key does not expose the pseudonyms of other organisations.
+func audienceKey(master []byte, audience string, scope pb.Scope) ([]byte, error) {
+	info := fmt.Appendf(nil, "pseudonym\x00%s\x00%s", audience, scope)
+	return crypto.DeriveKey(master, nil, info, len(master))
+}
+
+func CreatePseudonym(ps *pb.Pseudonym, keyring *keys.Keyring) (string, error) {
+	if ps.Scope == pb.Scope_RESEARCH {
+		return "", ErrResearchScope
+	}
+
+	keyID, master := keyring.Active()
+
+	key, err := audienceKey(master, ps.Audience, ps.Scope)
+	if err != nil {
+		return "", err
+	}
+
 	header := pb.Header{
-		Version:     pb.Version_V1,
+		Version:     pb.Version_V2,
 		ContentType: pb.ContentType_PSEUDONYM,
+		KeyId:       keyID,
 	}
 
 	pseudonymData, err := proto.Marshal(ps)
@@ -39,46 +75,62 @@ func CreatePseudonym(ps *pb.Pseudonym, key []byte) (string, error) {
 		Ciphertext: ciphertext,
 	}
 
-	tokenContainer, err := prototext.Marshal(&container)
-	if err != nil {
-		log.Fatal(err)
+	return encodeContainer(&container)
+}
+
+// DecryptPseudonum decrypts a pseudonym of the given audience and scope.
+// The audience and scope are needed to derive the key, a pseudonym of another audience can not be decrypted.
+func DecryptPseudonum(pseudonymString string, audience string, scope pb.Scope, keyring *keys.Keyring) (*pb.Pseudonym, error) {
+	if data, ok := DecodeCOSE(pseudonymString); ok {
+		return decryptPseudonymCOSE(data, audience, scope, keyring)
 	}
 
-	b64TokenContainer := base64.StdEncoding.EncodeToString(tokenContainer)
+	container, err := decodeContainer(pseudonymString)
+	if err != nil {
+		return nil, err
+	}
 
-	return b64TokenContainer, nil
-}
+	if container.Header.GetContentType() == pb.ContentType_RESEARCH_PSEUDONYM {
+		return nil, ErrIrreversiblePseudonym
+	}
 
-func DecryptPseudonum(pseudonymString string, key []byte) (*pb.Pseudonym, error) {
-	tokenContainer, err := base64.StdEncoding.DecodeString(pseudonymString)
-	if err != nil {
+	if err := checkHeader(container, pb.ContentType_PSEUDONYM); err != nil {
 		return nil, err
 	}
 
-	container := pb.Container{}
-	err = prototext.Unmarshal(tokenContainer, &container)
+	master, err := keyring.Key(container.Header.GetKeyId())
 	if err != nil {
 		return nil, err
 	}
 
-	aad := []byte{}
-	if container.Header != nil {
-		aad, err = proto.Marshal(container.Header)
+	// pseudonyms of version 1 were issued before keys were derived per audience, they are encrypted with the master key
+	key := master
+	if container.Header.Version != pb.Version_V1 {
+		key, err = audienceKey(master, audience, scope)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	aad, err := proto.Marshal(container.Header)
+	if err != nil {
+		return nil, err
+	}
+
 	// Decrypt the data using AES-GCM-SIV
 	plaintext, err := crypto.DecryptAESGCM_SIV(key, container.Nonce, container.Ciphertext, aad)
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed: %v", err)
+		return nil, decryptionError(err)
 	}
 
 	pseudonym := pb.Pseudonym{}
 	err = proto.Unmarshal([]byte(plaintext), &pseudonym)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	if pseudonym.Audience != audience || pseudonym.Scope != scope {
+		return nil, fmt.Errorf("%w: pseudonym does not belong to audience %s and scope %s", ErrDecryptionFailed, audience, scope)
 	}
 
 	return &pseudonym, nil
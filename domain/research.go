@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+)
+
+var (
+	// ErrIrreversiblePseudonym is returned when a research pseudonym is exchanged for the identifier of the subject.
+	ErrIrreversiblePseudonym = errors.New("research pseudonyms are irreversible")
+	// ErrResearchScope is returned when a reversible pseudonym is requested for the research scope.
+	ErrResearchScope = errors.New("pseudonyms for research must be irreversible, use a research pseudonym")
+	// ErrStudyRequired is returned when a research pseudonym is requested without a study.
+	ErrStudyRequired = errors.New("study is required for research pseudonyms")
+)
+
+// CreateResearchPseudonym creates an irreversible pseudonym of the subject for a study of the audience.
+// The pseudonym is a MAC of the subject with a key derived for the audience and study, so pseudonyms of the same
+// subject can not be linked between studies and nobody, including the service, can turn it back into the subject.
+func CreateResearchPseudonym(subject string, audience string, study string, keyring *keys.Keyring) (string, error) {
+	if study == "" {
+		return "", ErrStudyRequired
+	}
+
+	keyID, master := keyring.Active()
+
+	key, err := crypto.DeriveKey(master, nil, fmt.Appendf(nil, "research\x00%s\x00%s", audience, study), 32)
+	if err != nil {
+		return "", err
+	}
+
+	container := pb.Container{
+		Header: &pb.Header{
+			Version:     pb.Version_V1,
+			ContentType: pb.ContentType_RESEARCH_PSEUDONYM,
+			KeyId:       keyID,
+		},
+		Ciphertext: crypto.MAC(key, []byte(subject)),
+	}
+
+	return encodeContainer(&container)
+}
@@ -1,20 +1,65 @@
 package domain
 
 import (
+	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
 	pb "github.com/stevenvegt/pseudonyms/proto"
-	"google.golang.org/protobuf/encoding/prototext"
 	"google.golang.org/protobuf/proto"
 )
 
-func CreateToken(token *pb.Token, key []byte) (string, error) {
+var (
+	// ErrTokenExpired is returned when the expiration time of the token has passed.
+	ErrTokenExpired = errors.New("token has expired")
+	// ErrTokenNotYetValid is returned when the token is used before it was issued or before its not before time.
+	ErrTokenNotYetValid = errors.New("token is not yet valid")
+	// ErrWrongAudience is returned when a token is used by another organisation than it was issued for.
+	ErrWrongAudience = errors.New("token is not issued for this organisation")
+	// ErrScopeNotAllowed is returned when a token is used for a scope it was not issued for.
+	ErrScopeNotAllowed = errors.New("token is not issued for this scope")
+)
+
+// TokenFormat is the encoding of tokens.
+type TokenFormat string
+
+// TokenFormatContainer is the encrypted protobuf container, in the format of the receiver.
+const TokenFormatContainer TokenFormat = "container"
+
+// ParseTokenFormat returns the token format with the name, the container format when the name is empty.
+func ParseTokenFormat(name string) (TokenFormat, error) {
+	switch TokenFormat(name) {
+	case "", TokenFormatContainer:
+		return TokenFormatContainer, nil
+	case TokenFormatJWE:
+		return TokenFormatJWE, nil
+	case TokenFormatCWT:
+		return TokenFormatCWT, nil
+	case TokenFormatPASETO:
+		return TokenFormatPASETO, nil
+	default:
+		return "", fmt.Errorf("unknown token format: %s", name)
+	}
+}
+
+// CreateToken encrypts the token, a token without an ID gets a random ID.
+func CreateToken(token *pb.Token, keyring *keys.Keyring) (string, error) {
+	keyID, key := keyring.Active()
+
+	if err := ensureTokenID(token); err != nil {
+		return "", err
+	}
+
 	header := pb.Header{
 		Version:     pb.Version_V1,
 		ContentType: pb.ContentType_TOKEN,
+		KeyId:       keyID,
 	}
 
 	tokenData, err := proto.Marshal(token)
@@ -39,47 +84,133 @@ func CreateToken(token *pb.Token, key []byte) (string, error) {
 		Ciphertext: ciphertext,
 	}
 
-	tokenContainer, err := prototext.Marshal(&container)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	b64TokenContainer := base64.StdEncoding.EncodeToString(tokenContainer)
-
-	return b64TokenContainer, nil
+	return encodeContainer(&container)
 }
 
-func DecryptToken(tokenString string, key []byte) (*pb.Token, error) {
-	tokenContainer, err := base64.StdEncoding.DecodeString(tokenString)
+// DecryptToken decrypts a token, in a container, a PASETO, a JWE or a CWT.
+func DecryptToken(tokenString string, keyring *keys.Keyring) (*pb.Token, error) {
+	if isPASETO(tokenString) {
+		return decryptTokenPASETO(tokenString, keyring)
+	}
+	if isJWE(tokenString) {
+		return decryptTokenJWE(tokenString, keyring)
+	}
+	if data, ok := DecodeCOSE(tokenString); ok {
+		return decryptTokenCOSE(data, keyring)
+	}
+
+	container, err := decodeContainer(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	container := pb.Container{}
-	err = prototext.Unmarshal(tokenContainer, &container)
+	if err := checkHeader(container, pb.ContentType_TOKEN); err != nil {
+		return nil, err
+	}
+
+	key, err := keyring.Key(container.Header.GetKeyId())
 	if err != nil {
 		return nil, err
 	}
 
-	aad := []byte{}
-	if container.Header != nil {
-		aad, err = proto.Marshal(container.Header)
-		if err != nil {
-			return nil, err
-		}
+	aad, err := proto.Marshal(container.Header)
+	if err != nil {
+		return nil, err
 	}
 
 	// Decrypt the data using AES-GCM
 	plaintext, err := crypto.DecryptAESGCM(key, container.Nonce, container.Ciphertext, aad)
 	if err != nil {
-		return nil, fmt.Errorf("decryption failed: %v", err)
+		return nil, decryptionError(err)
 	}
 
 	token := pb.Token{}
 	err = proto.Unmarshal(plaintext, &token)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
 	}
 
 	return &token, nil
 }
+
+// newTokenID returns a random identifier of a token.
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(id), nil
+}
+
+// ensureTokenID gives a token without an ID a random ID.
+func ensureTokenID(token *pb.Token) error {
+	if token.Jti != "" {
+		return nil
+	}
+	jti, err := newTokenID()
+	if err != nil {
+		return err
+	}
+	token.Jti = jti
+	return nil
+}
+
+// scopeNames returns the names of the scopes separated by spaces, e.g. TREATMENT RESEARCH, the scope claim of the
+// JWE, CWT and PASETO formats.
+func scopeNames(scopes []pb.Scope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = scope.String()
+	}
+	return strings.Join(names, " ")
+}
+
+// parseScopeNames parses the names of scopes separated by spaces, no scopes when the names are empty.
+func parseScopeNames(names string) ([]pb.Scope, error) {
+	var scopes []pb.Scope
+	for _, name := range strings.Fields(names) {
+		scope, ok := pb.Scope_value[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown scope %s", ErrMalformed, name)
+		}
+		scopes = append(scopes, pb.Scope(scope))
+	}
+	return scopes, nil
+}
+
+// ValidateTokenLifetime checks that the token is valid at the given time.
+// The leeway allows for clock skew between the issuing and the validating server.
+func ValidateTokenLifetime(token *pb.Token, now time.Time, leeway time.Duration) error {
+	if token.Expiration == 0 {
+		return fmt.Errorf("%w: token has no expiration time", ErrTokenExpired)
+	}
+	if now.After(time.Unix(token.Expiration, 0).Add(leeway)) {
+		return ErrTokenExpired
+	}
+	if token.NotBefore != 0 && now.Before(time.Unix(token.NotBefore, 0).Add(-leeway)) {
+		return ErrTokenNotYetValid
+	}
+	if now.Before(time.Unix(token.IssuedAt, 0).Add(-leeway)) {
+		return fmt.Errorf("%w: token is issued in the future", ErrTokenNotYetValid)
+	}
+	return nil
+}
+
+// ValidateTokenAudience checks that the token is issued for the audience.
+func ValidateTokenAudience(token *pb.Token, audience string) error {
+	if token.Audience != audience {
+		return fmt.Errorf("%w: token audience is %s, not %s", ErrWrongAudience, token.Audience, audience)
+	}
+	return nil
+}
+
+// ValidateTokenScope checks that the scope is one of the scopes of the token.
+func ValidateTokenScope(token *pb.Token, scope pb.Scope) error {
+	if len(token.Scopes) == 0 {
+		return fmt.Errorf("%w: token has no scopes", ErrScopeNotAllowed)
+	}
+	if !slices.Contains(token.Scopes, scope) {
+		return fmt.Errorf("%w: %s is not in %v", ErrScopeNotAllowed, scope, token.Scopes)
+	}
+	return nil
+}
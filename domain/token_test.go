@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+	"github.com/stevenvegt/pseudonyms/keys"
+	pb "github.com/stevenvegt/pseudonyms/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecryptLegacyTokenAfterRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	// a token from before key identifiers, without key ID in its header
+	token := &pb.Token{Subject: "123456789", Audience: "ura:456", Jti: "legacy"}
+	header := &pb.Header{Version: pb.Version_V1, ContentType: pb.ContentType_TOKEN}
+	tokenData, err := proto.Marshal(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aad, err := proto.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, ciphertext, err := crypto.EncryptAESGCM(oldKey, tokenData, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenString, err := encodeContainer(&pb.Container{Nonce: nonce, Header: header, Ciphertext: ciphertext})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := keys.NewKeyring("new", map[string][]byte{"new": newKey, "old": oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err = rotated.WithLegacyKey("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := DecryptToken(tokenString, rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(decrypted, token) {
+		t.Fatalf("decrypted token differs:\n got %v\nwant %v", decrypted, token)
+	}
+
+	// new tokens are encrypted with the active key
+	if keyID, _ := rotated.Active(); keyID != "new" {
+		t.Fatalf("expected the new key to be active, got %s", keyID)
+	}
+}
@@ -0,0 +1,45 @@
+// Package expiry keeps entries which expire in a bbolt bucket, e.g. the IDs of used or revoked tokens.
+// The value of an entry is its expiration time.
+package expiry
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// EncodeTime encodes a time as the value of an entry, in seconds since the epoch.
+func EncodeTime(t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(t.Unix()))
+}
+
+// DecodeTime decodes the value of an entry.
+func DecodeTime(value []byte) time.Time {
+	return time.Unix(int64(binary.BigEndian.Uint64(value)), 0)
+}
+
+// Purge removes the entries of the bucket which have expired at the given time.
+func Purge(db *bolt.DB, bucket []byte, now time.Time) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+
+		// keys are collected first, deleting while iterating with a cursor skips keys
+		var expired [][]byte
+		err := b.ForEach(func(key, value []byte) error {
+			if !now.Before(DecodeTime(value)) {
+				expired = append(expired, append([]byte{}, key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := b.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
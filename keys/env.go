@@ -0,0 +1,56 @@
+package keys
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NewEnvKeyProvider returns a KeyProvider which reads base64 encoded keys from the given environment variables.
+// A variable can contain multiple comma separated keys to support key rotation, the first key is the active key and
+// the last key is the legacy key of the containers without key ID.
+func NewEnvKeyProvider(tokenVar, pseudonymVar string) (KeyProvider, error) {
+	tokenKeys, err := keyringFromEnv(tokenVar)
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonymKeys, err := keyringFromEnv(pseudonymVar)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStaticKeyProvider(tokenKeys, pseudonymKeys)
+}
+
+func keyringFromEnv(name string) (*Keyring, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	var (
+		activeID string
+		legacyID string
+		keys     = map[string][]byte{}
+	)
+	for _, encodedKey := range strings.Split(value, ",") {
+		key, err := ParseKey([]byte(encodedKey), FormatBase64)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s: %w", name, err)
+		}
+		id := KeyID(key)
+		if activeID == "" {
+			activeID = id
+		}
+		keys[id] = key
+		legacyID = id
+	}
+
+	// new keys are put in front, so the last key is the oldest, which was used before key identifiers
+	keyring, err := NewKeyring(activeID, keys)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.WithLegacyKey(legacyID)
+}
@@ -0,0 +1,142 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Format is the encoding of a key on disk.
+type Format string
+
+const (
+	// FormatRaw is a file containing the raw key bytes.
+	FormatRaw Format = "raw"
+	// FormatBase64 is a file containing the standard base64 encoded key.
+	FormatBase64 Format = "base64"
+	// FormatJWK is a file containing a symmetric JSON Web Key (RFC 7517) with key type "oct",
+	// or a JWK Set of which the first key is the active key and the last key is the legacy key of the containers
+	// without key ID.
+	FormatJWK Format = "jwk"
+)
+
+// NewFileKeyProvider returns a KeyProvider which reads the keys from the given files.
+func NewFileKeyProvider(tokenPath, pseudonymPath string, format Format) (KeyProvider, error) {
+	tokenKeys, err := keyringFromFile(tokenPath, format)
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonymKeys, err := keyringFromFile(pseudonymPath, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStaticKeyProvider(tokenKeys, pseudonymKeys)
+}
+
+func keyringFromFile(path string, format Format) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	if format == FormatJWK {
+		keys, err := parseJWKSet(data)
+		if err != nil {
+			return nil, fmt.Errorf("key file %s: %w", path, err)
+		}
+		return keys, nil
+	}
+
+	key, err := ParseKey(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("key file %s: %w", path, err)
+	}
+
+	return NewSingleKeyring(key), nil
+}
+
+// jwk contains the fields of a JSON Web Key needed for symmetric keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	K   string `json:"k"`
+}
+
+// jwkSet is a JSON Web Key Set, a file with a single JWK is treated as a set with one key.
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+func parseJWKSet(data []byte) (*Keyring, error) {
+	set := jwkSet{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		key := jwk{}
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("invalid JWK: %w", err)
+		}
+		set.Keys = []jwk{key}
+	}
+
+	var (
+		activeID string
+		legacyID string
+		keys     = map[string][]byte{}
+	)
+	for _, k := range set.Keys {
+		key, err := k.decode()
+		if err != nil {
+			return nil, err
+		}
+		id := k.Kid
+		if id == "" {
+			id = KeyID(key)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("duplicate key ID: %s", id)
+		}
+		if activeID == "" {
+			activeID = id
+		}
+		keys[id] = key
+		legacyID = id
+	}
+
+	// new keys are put in front, so the last key is the oldest, which was used before key identifiers
+	keyring, err := NewKeyring(activeID, keys)
+	if err != nil {
+		return nil, err
+	}
+	return keyring.WithLegacyKey(legacyID)
+}
+
+func (k jwk) decode() ([]byte, error) {
+	if k.Kty != "oct" {
+		return nil, fmt.Errorf("unsupported JWK key type: %s", k.Kty)
+	}
+	return base64.RawURLEncoding.DecodeString(k.K)
+}
+
+// ParseKey decodes a key in the given format.
+func ParseKey(data []byte, format Format) ([]byte, error) {
+	switch format {
+	case FormatRaw:
+		return data, nil
+	case FormatBase64:
+		return base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	case FormatJWK:
+		key := jwk{}
+		if err := json.Unmarshal(data, &key); err != nil {
+			return nil, fmt.Errorf("invalid JWK: %w", err)
+		}
+		return key.decode()
+	default:
+		return nil, fmt.Errorf("unsupported key format: %s", format)
+	}
+}
@@ -0,0 +1,98 @@
+package keys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrUnknownKey is returned when a container refers to a key which is not in the keyring.
+var ErrUnknownKey = errors.New("unknown key")
+
+// Keyring holds all keys which can be used for decryption and the active key which is used for encryption.
+// Old keys stay in the keyring after a rotation so previously issued tokens and pseudonyms can still be decrypted.
+type Keyring struct {
+	activeID string
+	// legacyID is the ID of the key of the containers created before key identifiers were introduced, empty when
+	// they are not decrypted.
+	legacyID string
+	keys     map[string][]byte
+}
+
+// NewKeyring creates a keyring with the given keys by their ID. The key with activeID is used for encryption.
+// A keyring with a single key decrypts the containers without key ID with that key, otherwise the legacy key has to
+// be set with WithLegacyKey.
+func NewKeyring(activeID string, keys map[string][]byte) (*Keyring, error) {
+	if activeID == "" {
+		return nil, fmt.Errorf("active key ID is required")
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %s is not in the keyring", activeID)
+	}
+	keyring := &Keyring{activeID: activeID, keys: keys}
+	if len(keys) == 1 {
+		keyring.legacyID = activeID
+	}
+	return keyring, nil
+}
+
+// NewSingleKeyring creates a keyring containing only the given key, identified by its KeyID.
+func NewSingleKeyring(key []byte) *Keyring {
+	id := KeyID(key)
+	return &Keyring{activeID: id, legacyID: id, keys: map[string][]byte{id: key}}
+}
+
+// WithLegacyKey returns a copy of the keyring which decrypts the containers without key ID with the key with the ID.
+// The legacy key is the key which was used before key identifiers were introduced, it does not change when the
+// active key is rotated.
+func (k *Keyring) WithLegacyKey(id string) (*Keyring, error) {
+	if _, ok := k.keys[id]; !ok {
+		return nil, fmt.Errorf("legacy key %s is not in the keyring", id)
+	}
+	return &Keyring{activeID: k.activeID, legacyID: id, keys: k.keys}, nil
+}
+
+// KeyID derives a key identifier from the key itself, used for keys which are not given an identifier.
+func KeyID(key []byte) string {
+	hash := sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
+
+// Active returns the ID and the key used for encryption.
+func (k *Keyring) Active() (string, []byte) {
+	return k.activeID, k.keys[k.activeID]
+}
+
+// Key returns the key with the given ID.
+// Containers created before key identifiers were introduced have no key ID and are decrypted with the legacy key.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	if id == "" {
+		if k.legacyID == "" {
+			return nil, fmt.Errorf("%w: no legacy key for a container without key ID", ErrUnknownKey)
+		}
+		id = k.legacyID
+	}
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// IDs returns the IDs of all keys in the keyring, in sorted order.
+func (k *Keyring) IDs() []string {
+	return slices.Sorted(maps.Keys(k.keys))
+}
+
+// checkKeyLengths checks the length of every key in the keyring.
+func (k *Keyring) checkKeyLengths(lengths ...int) error {
+	for id, key := range k.keys {
+		if err := checkKeyLength(key, lengths...); err != nil {
+			return fmt.Errorf("key %s: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestKeyringLegacyKey(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+	newerKey := bytes.Repeat([]byte{3}, 32)
+	encode := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name string
+		keys string
+	}{
+		{name: "before rotation", keys: encode(oldKey)},
+		{name: "after rotation", keys: encode(newKey) + "," + encode(oldKey)},
+		{name: "after second rotation", keys: encode(newerKey) + "," + encode(newKey) + "," + encode(oldKey)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TEST_KEYS", test.keys)
+			keyring, err := keyringFromEnv("TEST_KEYS")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// containers without key ID stay with the oldest key, the active key changes
+			key, err := keyring.Key("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(key, oldKey) {
+				t.Fatalf("expected the oldest key for containers without key ID")
+			}
+		})
+	}
+}
+
+func TestKeyringWithLegacyKey(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	keyring, err := NewKeyring("new", map[string][]byte{"old": oldKey, "new": newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with several keys it is not known which key is the legacy key
+	if _, err := keyring.Key(""); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected %v without legacy key, got %v", ErrUnknownKey, err)
+	}
+
+	legacy, err := keyring.WithLegacyKey("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := legacy.Key(""); err != nil || !bytes.Equal(key, oldKey) {
+		t.Fatalf("expected the legacy key, got %v", err)
+	}
+	if id, _ := legacy.Active(); id != "new" {
+		t.Fatalf("expected the active key to stay new, got %s", id)
+	}
+
+	if _, err := keyring.WithLegacyKey("unknown"); err == nil {
+		t.Fatal("expected an error for a legacy key which is not in the keyring")
+	}
+}
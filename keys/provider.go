@@ -0,0 +1,84 @@
+// Package keys provides the key material used to encrypt tokens and pseudonyms.
+package keys
+
+import "fmt"
+
+// KeyProvider provides the keys used by the pseudonym service.
+// Tokens and pseudonyms are always encrypted with distinct keys.
+type KeyProvider interface {
+	// TokenKeys returns the keyring used to encrypt tokens using AES-GCM.
+	TokenKeys() (*Keyring, error)
+	// PseudonymKeys returns the keyring used to encrypt pseudonyms using AES-GCM-SIV.
+	PseudonymKeys() (*Keyring, error)
+}
+
+// staticKeyProvider holds keys that are loaded once and never change.
+type staticKeyProvider struct {
+	tokenKeys     *Keyring
+	pseudonymKeys *Keyring
+}
+
+func (p *staticKeyProvider) TokenKeys() (*Keyring, error) {
+	return p.tokenKeys, nil
+}
+
+func (p *staticKeyProvider) PseudonymKeys() (*Keyring, error) {
+	return p.pseudonymKeys, nil
+}
+
+// NewMemoryKeyProvider returns a KeyProvider for keys that are already in memory, e.g. in tests.
+func NewMemoryKeyProvider(tokenKeys, pseudonymKeys *Keyring) (KeyProvider, error) {
+	return newStaticKeyProvider(tokenKeys, pseudonymKeys)
+}
+
+// WithLegacyKeys returns a KeyProvider which decrypts the containers without key ID with the token and pseudonym keys
+// with the IDs, e.g. when the oldest key is not the legacy key. An empty ID keeps the legacy key of the keyring.
+func WithLegacyKeys(provider KeyProvider, tokenID, pseudonymID string) (KeyProvider, error) {
+	tokenKeys, err := provider.TokenKeys()
+	if err != nil {
+		return nil, err
+	}
+	pseudonymKeys, err := provider.PseudonymKeys()
+	if err != nil {
+		return nil, err
+	}
+	if tokenID != "" {
+		if tokenKeys, err = tokenKeys.WithLegacyKey(tokenID); err != nil {
+			return nil, fmt.Errorf("invalid token key: %w", err)
+		}
+	}
+	if pseudonymID != "" {
+		if pseudonymKeys, err = pseudonymKeys.WithLegacyKey(pseudonymID); err != nil {
+			return nil, fmt.Errorf("invalid pseudonym key: %w", err)
+		}
+	}
+	return newStaticKeyProvider(tokenKeys, pseudonymKeys)
+}
+
+func newStaticKeyProvider(tokenKeys, pseudonymKeys *Keyring) (*staticKeyProvider, error) {
+	// AES-GCM accepts AES-128, AES-192 and AES-256 keys
+	if err := tokenKeys.checkKeyLengths(16, 24, 32); err != nil {
+		return nil, fmt.Errorf("invalid token key: %w", err)
+	}
+	// AES-GCM-SIV only accepts AES-128 and AES-256 keys
+	if err := pseudonymKeys.checkKeyLengths(16, 32); err != nil {
+		return nil, fmt.Errorf("invalid pseudonym key: %w", err)
+	}
+	for _, tokenKey := range tokenKeys.keys {
+		for _, pseudonymKey := range pseudonymKeys.keys {
+			if string(tokenKey) == string(pseudonymKey) {
+				return nil, fmt.Errorf("token and pseudonym keys must be different")
+			}
+		}
+	}
+	return &staticKeyProvider{tokenKeys: tokenKeys, pseudonymKeys: pseudonymKeys}, nil
+}
+
+func checkKeyLength(key []byte, lengths ...int) error {
+	for _, l := range lengths {
+		if len(key) == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("key length must be one of %v bytes, got %d", lengths, len(key))
+}
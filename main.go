@@ -1,26 +1,397 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/stevenvegt/pseudonyms/api"
+	"github.com/stevenvegt/pseudonyms/audit"
+	"github.com/stevenvegt/pseudonyms/auth"
+	"github.com/stevenvegt/pseudonyms/domain"
+	"github.com/stevenvegt/pseudonyms/keys"
+	"github.com/stevenvegt/pseudonyms/policy"
+	"github.com/stevenvegt/pseudonyms/registry"
+	"github.com/stevenvegt/pseudonyms/replay"
+	"github.com/stevenvegt/pseudonyms/revocation"
+	"github.com/stevenvegt/pseudonyms/versions"
 )
 
 func main() {
-	server := api.NewPseudonymService()
-	strictHandler := api.NewStrictHandler(server, nil)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify-audit":
+			verifyAuditLog(os.Args[2:])
+			return
+		case "export-pseudonym-key":
+			exportPseudonymKey(os.Args[2:])
+			return
+		case "export-jwe-keys":
+			exportJWEKeys(os.Args[2:])
+			return
+		default:
+			log.Fatalf("unknown command: %s, usage: %s [verify-audit <file> | export-pseudonym-key <organisation> <scope> | export-jwe-keys]", os.Args[1], os.Args[0])
+		}
+	}
+
+	keyProvider, err := newKeyProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		opts []api.Option
+		// closers are the stores which are closed when the server stops
+		closers []io.Closer
+	)
+	if revoked := os.Getenv("PRS_REVOKED_AUDIENCES"); revoked != "" {
+		opts = append(opts, api.WithRevokedAudiences(strings.Split(revoked, ",")...))
+	}
+	if leeway := os.Getenv("PRS_TOKEN_LEEWAY"); leeway != "" {
+		d, err := time.ParseDuration(leeway)
+		if err != nil {
+			log.Fatalf("invalid PRS_TOKEN_LEEWAY: %v", err)
+		}
+		opts = append(opts, api.WithLeeway(d))
+	}
+	if registryFile := os.Getenv("PRS_REGISTRY_FILE"); registryFile != "" {
+		organisations, err := registry.Load(registryFile)
+		if err != nil {
+			log.Fatalf("invalid PRS_REGISTRY_FILE: %v", err)
+		}
+		opts = append(opts, api.WithRegistry(organisations))
+	}
+	if policyFile := os.Getenv("PRS_POLICY_FILE"); policyFile != "" {
+		exchangePolicy, err := policy.Load(policyFile)
+		if err != nil {
+			log.Fatalf("invalid PRS_POLICY_FILE: %v", err)
+		}
+		opts = append(opts, api.WithPolicy(exchangePolicy))
+	}
+	if auditFile := os.Getenv("PRS_AUDIT_FILE"); auditFile != "" {
+		subjectKey, err := base64.StdEncoding.DecodeString(os.Getenv("PRS_AUDIT_KEY"))
+		if err != nil {
+			log.Fatalf("invalid PRS_AUDIT_KEY: %v", err)
+		}
+		auditLog, err := audit.OpenFileLog(auditFile, subjectKey)
+		if err != nil {
+			log.Fatalf("invalid PRS_AUDIT_FILE: %v", err)
+		}
+		closers = append(closers, auditLog)
+		opts = append(opts, api.WithAuditLog(auditLog))
+	}
+	if cacheFile := os.Getenv("PRS_REPLAY_CACHE_FILE"); cacheFile != "" {
+		replayCache, err := replay.OpenBoltCache(cacheFile)
+		if err != nil {
+			log.Fatalf("invalid PRS_REPLAY_CACHE_FILE: %v", err)
+		}
+		closers = append(closers, replayCache)
+		go purgePeriodically("replay cache", replayCache.Purge, time.Hour)
+		opts = append(opts, api.WithReplayCache(replayCache))
+	}
+	if revocationFile := os.Getenv("PRS_REVOCATION_FILE"); revocationFile != "" {
+		revocations, err := revocation.OpenBoltStore(revocationFile)
+		if err != nil {
+			log.Fatalf("invalid PRS_REVOCATION_FILE: %v", err)
+		}
+		closers = append(closers, revocations)
+		go purgePeriodically("revocation store", revocations.Purge, time.Hour)
+		opts = append(opts, api.WithRevocationStore(revocations))
+	}
+	if versionFile := os.Getenv("PRS_VERSION_FILE"); versionFile != "" {
+		subjectKey, err := base64.StdEncoding.DecodeString(os.Getenv("PRS_VERSION_KEY"))
+		if err != nil {
+			log.Fatalf("invalid PRS_VERSION_KEY: %v", err)
+		}
+		versionStore, err := versions.OpenBoltStore(versionFile, subjectKey)
+		if err != nil {
+			log.Fatalf("invalid PRS_VERSION_FILE: %v", err)
+		}
+		closers = append(closers, versionStore)
+		opts = append(opts, api.WithVersionStore(versionStore))
+	}
+	if tokenFormat := os.Getenv("PRS_TOKEN_FORMAT"); tokenFormat != "" {
+		format, err := domain.ParseTokenFormat(tokenFormat)
+		if err != nil {
+			log.Fatalf("invalid PRS_TOKEN_FORMAT: %v", err)
+		}
+		opts = append(opts, api.WithTokenFormat(format))
+	}
+	if singleUse := os.Getenv("PRS_SINGLE_USE_TOKENS"); singleUse != "" {
+		required, err := strconv.ParseBool(singleUse)
+		if err != nil {
+			log.Fatalf("invalid PRS_SINGLE_USE_TOKENS: %v", err)
+		}
+		if required {
+			opts = append(opts, api.WithSingleUseTokens())
+		}
+	}
+	if mapping := os.Getenv("PRS_SCOPE_MAPPING"); mapping != "" {
+		scopes, err := api.ParseScopeMapping(mapping)
+		if err != nil {
+			log.Fatalf("invalid PRS_SCOPE_MAPPING: %v", err)
+		}
+		opts = append(opts, api.WithScopeMapping(scopes))
+	}
+
+	// with mutual TLS the organisation of the caller is taken from its client certificate
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, api.WithRequiredIdentity())
+	}
+
+	// as an alternative to mutual TLS the caller is authenticated with a JWT access token
+	var middlewares []api.MiddlewareFunc
+	jwtConfig, err := newJWTConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if jwtConfig != nil {
+		if tlsConfig != nil {
+			log.Fatal("mutual TLS and access tokens can not be used together")
+		}
+		opts = append(opts, api.WithRequiredIdentity())
+		middlewares = append(middlewares, auth.BearerToken(*jwtConfig, api.WriteError))
+	}
+
+	server := api.NewPseudonymService(keyProvider, opts...)
+	strictHandler := api.NewStrictHandlerWithOptions(server, []api.StrictMiddlewareFunc{api.NegotiateContent}, api.ProblemHandlerOptions())
+
+	validator, err := api.NewRequestValidator()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	mux := http.NewServeMux()
-	handler := api.HandlerFromMux(strictHandler, mux)
+	handler := validator(api.HandlerWithOptions(strictHandler, api.StdHTTPServerOptions{
+		BaseRouter:  mux,
+		Middlewares: middlewares,
+	}))
+	if tlsConfig != nil {
+		handler = auth.ClientCertificate(api.WriteError)(handler)
+	}
 
 	s := &http.Server{
-		Handler: handler,
-		Addr:    "0.0.0.0:8080",
+		Handler:   handler,
+		Addr:      "0.0.0.0:8080",
+		TLSConfig: tlsConfig,
+	}
+
+	// the server stops on SIGINT and SIGTERM after the requests in progress are handled
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Printf("failed to shut down: %v", err)
+		}
+	}()
+
+	if tlsConfig != nil {
+		err = s.ListenAndServeTLS(os.Getenv("PRS_TLS_CERT_FILE"), os.Getenv("PRS_TLS_KEY_FILE"))
+	} else {
+		err = s.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdown
+	}
+
+	// log.Fatal does not run deferred calls, so the stores are closed explicitly
+	closeAll(closers)
+	if !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
+
+// closeAll closes the stores, so their files are synced and unlocked.
+func closeAll(closers []io.Closer) {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			log.Printf("failed to close: %v", err)
+		}
+	}
+}
+
+// verifyAuditLog verifies the hash chain of an audit log and exits with a non-zero status when it is broken.
+func verifyAuditLog(args []string) {
+	if len(args) != 1 {
+		log.Fatalf("usage: %s verify-audit <file>", os.Args[0])
+	}
+
+	records, err := audit.VerifyFile(args[0])
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %d records, hash chain is intact\n", args[0], records)
+}
+
+// exportPseudonymKey prints the keys with which an organisation decrypts its encrypted pseudonyms for a scope of the
+// API, as a JSON object of keys by key ID. There is a key for every pseudonym key, so it has to be exported again after
+// a key rotation.
+func exportPseudonymKey(args []string) {
+	if len(args) != 2 {
+		log.Fatalf("usage: %s export-pseudonym-key <organisation> <scope>", os.Args[0])
+	}
+
+	scopes := api.DefaultScopeMapping()
+	if mapping := os.Getenv("PRS_SCOPE_MAPPING"); mapping != "" {
+		var err error
+		if scopes, err = api.ParseScopeMapping(mapping); err != nil {
+			log.Fatalf("invalid PRS_SCOPE_MAPPING: %v", err)
+		}
+	}
+	scope, ok := scopes[api.Scope(args[1])]
+	if !ok {
+		log.Fatalf("unknown scope: %s", args[1])
+	}
+
+	keyProvider, err := newKeyProvider()
+	if err != nil {
+		log.Fatal(err)
 	}
+	keyring, err := keyProvider.PseudonymKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	organisationKeys, err := domain.OrganisationPseudonymKeys(args[0], scope, keyring)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.Marshal(organisationKeys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
 
-	// And we serve HTTP until the world ends.
-	log.Fatal(s.ListenAndServe())
+// exportJWEKeys prints the keys of JWE tokens as a JWK Set, for partners which decrypt JWE tokens with a JOSE library.
+// There is a key for every token key, so it has to be exported again after a key rotation.
+func exportJWEKeys(args []string) {
+	if len(args) != 0 {
+		log.Fatalf("usage: %s export-jwe-keys", os.Args[0])
+	}
+
+	keyProvider, err := newKeyProvider()
+	if err != nil {
+		log.Fatal(err)
+	}
+	keyring, err := keyProvider.TokenKeys()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keySet, err := domain.JWEKeySet(keyring)
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.Marshal(keySet)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(data))
+}
+
+// purgePeriodically removes the entries of expired tokens from a store.
+func purgePeriodically(name string, purge func() error, interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := purge(); err != nil {
+			log.Printf("failed to purge %s: %v", name, err)
+		}
+	}
+}
+
+// newJWTConfig returns the configuration to verify access tokens when PRS_JWKS_FILE is set, and nil otherwise.
+func newJWTConfig() (*auth.JWTConfig, error) {
+	jwksFile := os.Getenv("PRS_JWKS_FILE")
+	if jwksFile == "" {
+		return nil, nil
+	}
+
+	keySet, err := auth.LoadJWKS(jwksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &auth.JWTConfig{
+		KeySet:            keySet,
+		Issuer:            os.Getenv("PRS_JWT_ISSUER"),
+		Audience:          os.Getenv("PRS_JWT_AUDIENCE"),
+		OrganisationClaim: os.Getenv("PRS_JWT_ORGANISATION_CLAIM"),
+		Leeway:            30 * time.Second,
+	}
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, fmt.Errorf("PRS_JWT_ISSUER and PRS_JWT_AUDIENCE are required for access tokens")
+	}
+	return config, nil
+}
+
+// newTLSConfig returns the configuration for mutual TLS when PRS_TLS_CLIENT_CA_FILE is set, and nil otherwise.
+// Only clients with a certificate issued by one of the CAs in the file are accepted.
+func newTLSConfig() (*tls.Config, error) {
+	clientCAFile := os.Getenv("PRS_TLS_CLIENT_CA_FILE")
+	if clientCAFile == "" {
+		return nil, nil
+	}
+	if os.Getenv("PRS_TLS_CERT_FILE") == "" || os.Getenv("PRS_TLS_KEY_FILE") == "" {
+		return nil, fmt.Errorf("PRS_TLS_CERT_FILE and PRS_TLS_KEY_FILE are required for mutual TLS")
+	}
+
+	clientCAs, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(clientCAs) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
+
+// newKeyProvider loads the keys from files when PRS_TOKEN_KEY_FILE is set, and from environment variables otherwise.
+func newKeyProvider() (keys.KeyProvider, error) {
+	var (
+		keyProvider keys.KeyProvider
+		err         error
+	)
+	if tokenKeyFile := os.Getenv("PRS_TOKEN_KEY_FILE"); tokenKeyFile != "" {
+		format := keys.Format(os.Getenv("PRS_KEY_FORMAT"))
+		if format == "" {
+			format = keys.FormatBase64
+		}
+		keyProvider, err = keys.NewFileKeyProvider(tokenKeyFile, os.Getenv("PRS_PSEUDONYM_KEY_FILE"), format)
+	} else {
+		keyProvider, err = keys.NewEnvKeyProvider("PRS_TOKEN_KEY", "PRS_PSEUDONYM_KEY")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keys.WithLegacyKeys(keyProvider, os.Getenv("PRS_LEGACY_TOKEN_KEY_ID"), os.Getenv("PRS_LEGACY_PSEUDONYM_KEY_ID"))
 }
 
 // Example usage function
@@ -0,0 +1,156 @@
+// Package policy decides which exchanges of identifiers are permitted, based on a declarative set of rules.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrDenied is returned when an exchange is not permitted by the policy.
+var ErrDenied = errors.New("exchange is denied by policy")
+
+// Token is the identifier type of tokens, the source of a token exchange and the target of a token request.
+const Token = "TOKEN"
+
+// OPRF is the identifier type of pseudonyms which are evaluated with the OPRF, the target of an OPRF evaluation.
+// The source is a BSN, which is blinded by the client.
+const OPRF = "OPRF_PSEUDO"
+
+// Effect is the outcome of a rule when it matches an exchange.
+type Effect string
+
+const (
+	// Allow permits the exchange.
+	Allow Effect = "allow"
+	// Deny denies the exchange.
+	Deny Effect = "deny"
+)
+
+func (e Effect) past() string {
+	if e == Allow {
+		return "allowed"
+	}
+	return "denied"
+}
+
+// Rule matches exchanges by the values of their fields. An empty list, or a list containing "*", matches any value.
+type Rule struct {
+	// Name identifies the rule in decisions.
+	Name string `yaml:"name"`
+	// Effect is applied to the exchanges which match the rule.
+	Effect Effect `yaml:"effect"`
+	// Sources are the identifier types which are exchanged, e.g. ORGANISATION_PSEUDO or TOKEN.
+	Sources []string `yaml:"sources"`
+	// Targets are the identifier types which are requested, e.g. BSN or TOKEN.
+	Targets []string `yaml:"targets"`
+	// Scopes are the scopes of the API, e.g. zorg.
+	Scopes []string `yaml:"scopes"`
+	// Callers are the organisations which request the exchange.
+	Callers []string `yaml:"callers"`
+	// Audiences are the organisations which receive the result of the exchange.
+	Audiences []string `yaml:"audiences"`
+}
+
+func (r Rule) matches(request Request) bool {
+	return matches(r.Sources, request.Source) &&
+		matches(r.Targets, request.Target) &&
+		matches(r.Scopes, request.Scope) &&
+		matches(r.Callers, request.Caller) &&
+		matches(r.Audiences, request.Audience)
+}
+
+func matches(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, "*") || slices.Contains(values, value)
+}
+
+// Request is an exchange which is evaluated by the policy.
+type Request struct {
+	Source   string
+	Target   string
+	Scope    string
+	Caller   string
+	Audience string
+}
+
+// Decision is the outcome of the evaluation of an exchange.
+type Decision struct {
+	// Allowed reports whether the exchange is permitted.
+	Allowed bool
+	// Rule is the name of the rule which matched the exchange, empty when the default was applied.
+	Rule string
+	// Reason explains the decision.
+	Reason string
+}
+
+// Policy is an ordered list of rules, the first rule which matches an exchange decides.
+// When no rule matches, the default is applied, which denies the exchange unless it is set to allow.
+type Policy struct {
+	Rules   []Rule `yaml:"rules"`
+	Default Effect `yaml:"default"`
+}
+
+// Load reads a policy from a YAML or JSON file, e.g.
+//
+//	default: deny
+//	rules:
+//	  - name: depseudonymise-own-pseudonyms
+//	    effect: allow
+//	    sources: [ORGANISATION_PSEUDO]
+//	    targets: [BSN]
+//	    callers: [ura:12345678]
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a policy in YAML or JSON, JSON is parsed as a subset of YAML. Unknown fields are rejected, a misspelled
+// field would otherwise leave a list empty, which matches every value.
+func Parse(data []byte) (*Policy, error) {
+	policy := Policy{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&policy); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid policy: %v", err)
+	}
+	if policy.Default == "" {
+		policy.Default = Deny
+	}
+	if policy.Default != Allow && policy.Default != Deny {
+		return nil, fmt.Errorf("invalid policy: unknown default %q", policy.Default)
+	}
+	for i, rule := range policy.Rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("invalid policy: rule %d has no name", i)
+		}
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return nil, fmt.Errorf("invalid policy: rule %s has unknown effect %q", rule.Name, rule.Effect)
+		}
+	}
+	return &policy, nil
+}
+
+// Evaluate decides whether the exchange is permitted.
+func (p *Policy) Evaluate(request Request) Decision {
+	for _, rule := range p.Rules {
+		if rule.matches(request) {
+			return Decision{
+				Allowed: rule.Effect == Allow,
+				Rule:    rule.Name,
+				Reason:  fmt.Sprintf("%s by rule %s", rule.Effect.past(), rule.Name),
+			}
+		}
+	}
+	return Decision{
+		Allowed: p.Default == Allow,
+		Reason:  fmt.Sprintf("no rule matches, %s by default", p.Default.past()),
+	}
+}
@@ -0,0 +1,102 @@
+package policy
+
+import "testing"
+
+const testPolicy = `
+rules:
+  - name: deny-research-bsn
+    effect: deny
+    targets: [BSN]
+    scopes: [onderzoek]
+  - name: depseudonymise-own-pseudonyms
+    effect: allow
+    sources: [ORGANISATION_PSEUDO]
+    targets: [BSN]
+    callers: [ura:1]
+  - name: tokens
+    effect: allow
+    sources: [TOKEN]
+    callers: ["*"]
+`
+
+func TestEvaluate(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		request Request
+		allowed bool
+		rule    string
+	}{
+		{
+			name:    "allowed by rule",
+			request: Request{Source: "ORGANISATION_PSEUDO", Target: "BSN", Scope: "zorg", Caller: "ura:1", Audience: "ura:1"},
+			allowed: true,
+			rule:    "depseudonymise-own-pseudonyms",
+		},
+		{
+			name:    "first match wins",
+			request: Request{Source: "ORGANISATION_PSEUDO", Target: "BSN", Scope: "onderzoek", Caller: "ura:1", Audience: "ura:1"},
+			allowed: false,
+			rule:    "deny-research-bsn",
+		},
+		{
+			name:    "wildcard",
+			request: Request{Source: Token, Target: "ORGANISATION_PSEUDO", Scope: "zorg", Caller: "ura:2", Audience: "ura:2"},
+			allowed: true,
+			rule:    "tokens",
+		},
+		{
+			name:    "denied by default",
+			request: Request{Source: "ORGANISATION_PSEUDO", Target: "BSN", Scope: "zorg", Caller: "ura:2", Audience: "ura:2"},
+			allowed: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := policy.Evaluate(test.request)
+			if decision.Allowed != test.allowed || decision.Rule != test.rule {
+				t.Fatalf("expected allowed %v by rule %q, got %+v", test.allowed, test.rule, decision)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "empty", data: ""},
+		{name: "JSON", data: `{"default": "allow", "rules": [{"name": "all", "effect": "deny"}]}`},
+		{name: "unknown field", data: "rules:\n  - name: typo\n    effect: allow\n    caller: [ura:1]\n", wantErr: true},
+		{name: "unknown top-level field", data: "defaults: allow\n", wantErr: true},
+		{name: "unknown default", data: "default: maybe\n", wantErr: true},
+		{name: "rule without name", data: "rules:\n  - effect: allow\n", wantErr: true},
+		{name: "unknown effect", data: "rules:\n  - name: all\n    effect: permit\n", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy, err := Parse([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// an empty policy denies by default
+	policy, _ := Parse(nil)
+	if decision := policy.Evaluate(Request{Source: Token, Target: "BSN"}); decision.Allowed {
+		t.Fatalf("expected the empty policy to deny, got %+v", decision)
+	}
+}
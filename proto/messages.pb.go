@@ -24,15 +24,19 @@ type Version int32
 
 const (
 	Version_V1 Version = 0
+	// pseudonyms are encrypted with a key derived per audience and scope with HKDF, instead of the pseudonym key itself.
+	Version_V2 Version = 1
 )
 
 // Enum value maps for Version.
 var (
 	Version_name = map[int32]string{
 		0: "V1",
+		1: "V2",
 	}
 	Version_value = map[string]int32{
 		"V1": 0,
+		"V2": 1,
 	}
 )
 
@@ -66,8 +70,12 @@ func (Version) EnumDescriptor() ([]byte, []int) {
 type ContentType int32
 
 const (
-	ContentType_TOKEN     ContentType = 0
-	ContentType_PSEUDONYM ContentType = 1
+	ContentType_TOKEN                 ContentType = 0
+	ContentType_PSEUDONYM             ContentType = 1
+	ContentType_POLYMORPHIC_PSEUDONYM ContentType = 2
+	ContentType_ENCRYPTED_PSEUDONYM   ContentType = 3
+	// irreversible pseudonym for research, the ciphertext of the container is a MAC of the subject.
+	ContentType_RESEARCH_PSEUDONYM ContentType = 4
 )
 
 // Enum value maps for ContentType.
@@ -75,10 +83,16 @@ var (
 	ContentType_name = map[int32]string{
 		0: "TOKEN",
 		1: "PSEUDONYM",
+		2: "POLYMORPHIC_PSEUDONYM",
+		3: "ENCRYPTED_PSEUDONYM",
+		4: "RESEARCH_PSEUDONYM",
 	}
 	ContentType_value = map[string]int32{
-		"TOKEN":     0,
-		"PSEUDONYM": 1,
+		"TOKEN":                 0,
+		"PSEUDONYM":             1,
+		"POLYMORPHIC_PSEUDONYM": 2,
+		"ENCRYPTED_PSEUDONYM":   3,
+		"RESEARCH_PSEUDONYM":    4,
 	}
 )
 
@@ -160,7 +174,9 @@ type Header struct {
 	// version of the pseudonym implementation
 	Version Version `protobuf:"varint,1,opt,name=version,enum=main.Version" json:"version,omitempty"`
 	// content type of the container, could be a token or a pseudonym.
-	ContentType   ContentType `protobuf:"varint,2,opt,name=content_type,json=contentType,enum=main.ContentType" json:"content_type,omitempty"`
+	ContentType ContentType `protobuf:"varint,2,opt,name=content_type,json=contentType,enum=main.ContentType" json:"content_type,omitempty"`
+	// identifier of the key used to encrypt the container, used to find the key when decrypting.
+	KeyId         string `protobuf:"bytes,3,opt,name=key_id,json=keyId" json:"key_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -209,6 +225,13 @@ func (x *Header) GetContentType() ContentType {
 	return ContentType_TOKEN
 }
 
+func (x *Header) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
 type Container struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Header        *Header                `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
@@ -282,7 +305,13 @@ type Token struct {
 	// time the token was issued in seconds since epoch.
 	IssuedAt int64 `protobuf:"varint,5,opt,name=issued_at,json=issuedAt" json:"issued_at,omitempty"`
 	// scopes that the token can be used for.
-	Scopes        []Scope `protobuf:"varint,6,rep,packed,name=scopes,enum=main.Scope" json:"scopes,omitempty"`
+	Scopes []Scope `protobuf:"varint,6,rep,packed,name=scopes,enum=main.Scope" json:"scopes,omitempty"`
+	// time before which the token must not be accepted in seconds since epoch.
+	NotBefore int64 `protobuf:"varint,7,opt,name=not_before,json=notBefore" json:"not_before,omitempty"`
+	// unique identifier of the token, used to detect replay of single-use tokens.
+	Jti string `protobuf:"bytes,8,opt,name=jti" json:"jti,omitempty"`
+	// single-use tokens can only be exchanged once.
+	SingleUse     bool `protobuf:"varint,9,opt,name=single_use,json=singleUse" json:"single_use,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -359,6 +388,27 @@ func (x *Token) GetScopes() []Scope {
 	return nil
 }
 
+func (x *Token) GetNotBefore() int64 {
+	if x != nil {
+		return x.NotBefore
+	}
+	return 0
+}
+
+func (x *Token) GetJti() string {
+	if x != nil {
+		return x.Jti
+	}
+	return ""
+}
+
+func (x *Token) GetSingleUse() bool {
+	if x != nil {
+		return x.SingleUse
+	}
+	return false
+}
+
 type Pseudonym struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// identifier of the subject, e.g. a BSN of a patient id.
@@ -366,7 +416,7 @@ type Pseudonym struct {
 	// identifief of the organization that the pseudonym is created for.
 	Audience string `protobuf:"bytes,2,opt,name=audience" json:"audience,omitempty"`
 	// version which can be incremented in case the pseudonym has been compromised.
-	// Discussion if this is needed. This requires state at the server. Could also later be added in a new version.
+	// The current version of a subject and audience is kept by the server, older versions are rejected.
 	Version int32 `protobuf:"varint,3,opt,name=version" json:"version,omitempty"`
 	// scope that the pseudonym can be used for.
 	Scope         Scope `protobuf:"varint,4,opt,name=scope,enum=main.Scope" json:"scope,omitempty"`
@@ -432,54 +482,134 @@ func (x *Pseudonym) GetScope() Scope {
 	return Scope_TREATMENT
 }
 
+// ElGamal ciphertext on the ristretto255 group, used for polymorphic and encrypted pseudonyms.
+// It is stored unencrypted in the ciphertext of a Container as it is already encrypted.
+type ElGamalCiphertext struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ephemeral public key r*G.
+	B []byte `protobuf:"bytes,1,opt,name=b" json:"b,omitempty"`
+	// encrypted message M + r*Y.
+	C []byte `protobuf:"bytes,2,opt,name=c" json:"c,omitempty"`
+	// public key Y the message is encrypted for.
+	Y             []byte `protobuf:"bytes,3,opt,name=y" json:"y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ElGamalCiphertext) Reset() {
+	*x = ElGamalCiphertext{}
+	mi := &file_proto_messages_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ElGamalCiphertext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ElGamalCiphertext) ProtoMessage() {}
+
+func (x *ElGamalCiphertext) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_messages_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ElGamalCiphertext.ProtoReflect.Descriptor instead.
+func (*ElGamalCiphertext) Descriptor() ([]byte, []int) {
+	return file_proto_messages_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ElGamalCiphertext) GetB() []byte {
+	if x != nil {
+		return x.B
+	}
+	return nil
+}
+
+func (x *ElGamalCiphertext) GetC() []byte {
+	if x != nil {
+		return x.C
+	}
+	return nil
+}
+
+func (x *ElGamalCiphertext) GetY() []byte {
+	if x != nil {
+		return x.Y
+	}
+	return nil
+}
+
 var File_proto_messages_proto protoreflect.FileDescriptor
 
 var file_proto_messages_proto_rawDesc = []byte{
 	0x0a, 0x14, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x73,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6d, 0x61, 0x69, 0x6e, 0x22, 0x67, 0x0a, 0x06,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6d, 0x61, 0x69, 0x6e, 0x22, 0x7e, 0x0a, 0x06,
 	0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x27, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
 	0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x56,
 	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
 	0x34, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18,
 	0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x6f, 0x6e,
 	0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x52, 0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
-	0x74, 0x54, 0x79, 0x70, 0x65, 0x22, 0x67, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e,
-	0x65, 0x72, 0x12, 0x24, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72,
-	0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x1e,
-	0x0a, 0x0a, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x18, 0x03, 0x20, 0x01,
-	0x28, 0x0c, 0x52, 0x0a, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74, 0x65, 0x78, 0x74, 0x22, 0xb7,
-	0x01, 0x0a, 0x05, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a,
-	0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65,
-	0x63, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x73, 0x75, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x06, 0x69, 0x73, 0x73, 0x75, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x75,
-	0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x75,
-	0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x69,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x73, 0x73, 0x75, 0x65, 0x64,
-	0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x69, 0x73, 0x73, 0x75, 0x65,
-	0x64, 0x41, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x18, 0x06, 0x20,
-	0x03, 0x28, 0x0e, 0x32, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65,
-	0x52, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x22, 0x7e, 0x0a, 0x09, 0x50, 0x73, 0x65, 0x75,
-	0x64, 0x6f, 0x6e, 0x79, 0x6d, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12,
-	0x1a, 0x0a, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76,
-	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65,
-	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0e, 0x32, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x63, 0x6f, 0x70,
-	0x65, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x2a, 0x11, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x31, 0x10, 0x00, 0x2a, 0x27, 0x0a, 0x0b, 0x43,
-	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x09, 0x0a, 0x05, 0x54, 0x4f,
-	0x4b, 0x45, 0x4e, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x50, 0x53, 0x45, 0x55, 0x44, 0x4f, 0x4e,
-	0x59, 0x4d, 0x10, 0x01, 0x2a, 0x24, 0x0a, 0x05, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x0d, 0x0a,
-	0x09, 0x54, 0x52, 0x45, 0x41, 0x54, 0x4d, 0x45, 0x4e, 0x54, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08,
-	0x52, 0x45, 0x53, 0x45, 0x41, 0x52, 0x43, 0x48, 0x10, 0x01, 0x42, 0x2d, 0x5a, 0x26, 0x67, 0x69,
-	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x65, 0x76, 0x65, 0x6e, 0x76,
-	0x65, 0x67, 0x74, 0x2f, 0x70, 0x73, 0x65, 0x75, 0x64, 0x6f, 0x6e, 0x79, 0x6d, 0x73, 0x2f, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x92, 0x03, 0x02, 0x08, 0x02, 0x62, 0x08, 0x65, 0x64, 0x69, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x70, 0xe8, 0x07,
+	0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x15, 0x0a, 0x06, 0x6b, 0x65, 0x79, 0x5f, 0x69, 0x64, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6b, 0x65, 0x79, 0x49, 0x64, 0x22, 0x67, 0x0a, 0x09,
+	0x43, 0x6f, 0x6e, 0x74, 0x61, 0x69, 0x6e, 0x65, 0x72, 0x12, 0x24, 0x0a, 0x06, 0x68, 0x65, 0x61,
+	0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
+	0x2e, 0x48, 0x65, 0x61, 0x64, 0x65, 0x72, 0x52, 0x06, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x12,
+	0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
+	0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x69, 0x70, 0x68, 0x65, 0x72, 0x74,
+	0x65, 0x78, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x63, 0x69, 0x70, 0x68, 0x65,
+	0x72, 0x74, 0x65, 0x78, 0x74, 0x22, 0x87, 0x02, 0x0a, 0x05, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x12,
+	0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x69, 0x73, 0x73,
+	0x75, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x69, 0x73, 0x73, 0x75, 0x65,
+	0x72, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x1e, 0x0a,
+	0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a,
+	0x09, 0x69, 0x73, 0x73, 0x75, 0x65, 0x64, 0x5f, 0x61, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x69, 0x73, 0x73, 0x75, 0x65, 0x64, 0x41, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x63,
+	0x6f, 0x70, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0b, 0x2e, 0x6d, 0x61, 0x69,
+	0x6e, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x52, 0x06, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x73, 0x12,
+	0x1d, 0x0a, 0x0a, 0x6e, 0x6f, 0x74, 0x5f, 0x62, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x09, 0x6e, 0x6f, 0x74, 0x42, 0x65, 0x66, 0x6f, 0x72, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x6a, 0x74, 0x69, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6a, 0x74, 0x69,
+	0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x5f, 0x75, 0x73, 0x65, 0x18, 0x09,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x73, 0x69, 0x6e, 0x67, 0x6c, 0x65, 0x55, 0x73, 0x65, 0x22,
+	0x7e, 0x0a, 0x09, 0x50, 0x73, 0x65, 0x75, 0x64, 0x6f, 0x6e, 0x79, 0x6d, 0x12, 0x18, 0x0a, 0x07,
+	0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73,
+	0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e,
+	0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x61, 0x75, 0x64, 0x69, 0x65, 0x6e,
+	0x63, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x05,
+	0x73, 0x63, 0x6f, 0x70, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0b, 0x2e, 0x6d, 0x61,
+	0x69, 0x6e, 0x2e, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x52, 0x05, 0x73, 0x63, 0x6f, 0x70, 0x65, 0x22,
+	0x3d, 0x0a, 0x11, 0x45, 0x6c, 0x47, 0x61, 0x6d, 0x61, 0x6c, 0x43, 0x69, 0x70, 0x68, 0x65, 0x72,
+	0x74, 0x65, 0x78, 0x74, 0x12, 0x0c, 0x0a, 0x01, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x01, 0x62, 0x12, 0x0c, 0x0a, 0x01, 0x63, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x63,
+	0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x01, 0x79, 0x2a, 0x19,
+	0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x31, 0x10,
+	0x00, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x32, 0x10, 0x01, 0x2a, 0x73, 0x0a, 0x0b, 0x43, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x09, 0x0a, 0x05, 0x54, 0x4f, 0x4b, 0x45,
+	0x4e, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x50, 0x53, 0x45, 0x55, 0x44, 0x4f, 0x4e, 0x59, 0x4d,
+	0x10, 0x01, 0x12, 0x19, 0x0a, 0x15, 0x50, 0x4f, 0x4c, 0x59, 0x4d, 0x4f, 0x52, 0x50, 0x48, 0x49,
+	0x43, 0x5f, 0x50, 0x53, 0x45, 0x55, 0x44, 0x4f, 0x4e, 0x59, 0x4d, 0x10, 0x02, 0x12, 0x17, 0x0a,
+	0x13, 0x45, 0x4e, 0x43, 0x52, 0x59, 0x50, 0x54, 0x45, 0x44, 0x5f, 0x50, 0x53, 0x45, 0x55, 0x44,
+	0x4f, 0x4e, 0x59, 0x4d, 0x10, 0x03, 0x12, 0x16, 0x0a, 0x12, 0x52, 0x45, 0x53, 0x45, 0x41, 0x52,
+	0x43, 0x48, 0x5f, 0x50, 0x53, 0x45, 0x55, 0x44, 0x4f, 0x4e, 0x59, 0x4d, 0x10, 0x04, 0x2a, 0x24,
+	0x0a, 0x05, 0x53, 0x63, 0x6f, 0x70, 0x65, 0x12, 0x0d, 0x0a, 0x09, 0x54, 0x52, 0x45, 0x41, 0x54,
+	0x4d, 0x45, 0x4e, 0x54, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x53, 0x45, 0x41, 0x52,
+	0x43, 0x48, 0x10, 0x01, 0x42, 0x2d, 0x5a, 0x26, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x73, 0x74, 0x65, 0x76, 0x65, 0x6e, 0x76, 0x65, 0x67, 0x74, 0x2f, 0x70, 0x73,
+	0x65, 0x75, 0x64, 0x6f, 0x6e, 0x79, 0x6d, 0x73, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x92, 0x03,
+	0x02, 0x08, 0x02, 0x62, 0x08, 0x65, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x70, 0xe8, 0x07,
 }
 
 var (
@@ -495,15 +625,16 @@ func file_proto_messages_proto_rawDescGZIP() []byte {
 }
 
 var file_proto_messages_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_proto_messages_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
 var file_proto_messages_proto_goTypes = []any{
-	(Version)(0),      // 0: main.Version
-	(ContentType)(0),  // 1: main.ContentType
-	(Scope)(0),        // 2: main.Scope
-	(*Header)(nil),    // 3: main.Header
-	(*Container)(nil), // 4: main.Container
-	(*Token)(nil),     // 5: main.Token
-	(*Pseudonym)(nil), // 6: main.Pseudonym
+	(Version)(0),              // 0: main.Version
+	(ContentType)(0),          // 1: main.ContentType
+	(Scope)(0),                // 2: main.Scope
+	(*Header)(nil),            // 3: main.Header
+	(*Container)(nil),         // 4: main.Container
+	(*Token)(nil),             // 5: main.Token
+	(*Pseudonym)(nil),         // 6: main.Pseudonym
+	(*ElGamalCiphertext)(nil), // 7: main.ElGamalCiphertext
 }
 var file_proto_messages_proto_depIdxs = []int32{
 	0, // 0: main.Header.version:type_name -> main.Version
@@ -529,7 +660,7 @@ func file_proto_messages_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_proto_messages_proto_rawDesc,
 			NumEnums:      3,
-			NumMessages:   4,
+			NumMessages:   5,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
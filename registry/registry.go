@@ -0,0 +1,169 @@
+// Package registry holds the organisations which may use the pseudonym service and what they are allowed to do.
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/stevenvegt/pseudonyms/domain"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownOrganisation is returned when an organisation is not in the registry.
+	ErrUnknownOrganisation = errors.New("unknown organisation")
+	// ErrNotAllowed is returned when an organisation is not allowed to use a scope or identifier type.
+	ErrNotAllowed = errors.New("organisation is not allowed")
+)
+
+// bsn is the identifier type of the BSN, which is allowed by Depseudonymise instead of IdentifierTypes.
+const bsn = "BSN"
+
+// Organisation is an organisation which may use the pseudonym service.
+type Organisation struct {
+	// ID is the identifier of the organisation, e.g. ura:12345678.
+	ID string `yaml:"id"`
+	// Name is the human readable name of the organisation.
+	Name string `yaml:"name"`
+	// Scopes are the scopes of the API the organisation may use, e.g. zorg.
+	Scopes []string `yaml:"scopes"`
+	// IdentifierTypes are the types of pseudonyms the organisation may receive, e.g. ORGANISATION_PSEUDO.
+	IdentifierTypes []string `yaml:"identifierTypes"`
+	// Depseudonymise allows the organisation to exchange pseudonyms and tokens for the BSN of the subject.
+	Depseudonymise bool `yaml:"depseudonymise"`
+	// Admin allows the organisation to use the administrative endpoints, e.g. to bump the version of pseudonyms.
+	Admin bool `yaml:"admin"`
+	// Format is the format of the tokens and pseudonyms the organisation receives, compact when empty.
+	Format domain.Format `yaml:"format"`
+	// TokenFormat is the format of the tokens the organisation receives, e.g. jwe. The format of the service is used
+	// when it is empty.
+	TokenFormat domain.TokenFormat `yaml:"tokenFormat"`
+	// PseudonymFormat is the format of the organisation pseudonyms the organisation receives, e.g. cose. It is the
+	// same for every request, so the organisation has one pseudonym for a subject.
+	PseudonymFormat domain.PseudonymFormat `yaml:"pseudonymFormat"`
+}
+
+// AllowsScope reports whether the organisation may use the scope.
+func (o Organisation) AllowsScope(scope string) bool {
+	return slices.Contains(o.Scopes, scope)
+}
+
+// AllowsIdentifierType reports whether the organisation may receive identifiers of the type.
+func (o Organisation) AllowsIdentifierType(identifierType string) bool {
+	if identifierType == bsn {
+		return o.Depseudonymise
+	}
+	return slices.Contains(o.IdentifierTypes, identifierType)
+}
+
+// Registry holds the organisations which may use the pseudonym service.
+type Registry struct {
+	organisations map[string]Organisation
+}
+
+type registryFile struct {
+	Organisations []Organisation `yaml:"organisations"`
+}
+
+// New creates a registry of the organisations, every organisation must have a unique ID.
+func New(organisations []Organisation) (*Registry, error) {
+	r := &Registry{organisations: map[string]Organisation{}}
+	for _, organisation := range organisations {
+		if organisation.ID == "" {
+			return nil, fmt.Errorf("organisation without id")
+		}
+		if _, ok := r.organisations[organisation.ID]; ok {
+			return nil, fmt.Errorf("duplicate organisation: %s", organisation.ID)
+		}
+		if slices.Contains(organisation.IdentifierTypes, bsn) {
+			return nil, fmt.Errorf("organisation %s: use depseudonymise to allow the BSN", organisation.ID)
+		}
+		format, err := domain.ParseFormat(string(organisation.Format))
+		if err != nil {
+			return nil, fmt.Errorf("organisation %s: %v", organisation.ID, err)
+		}
+		organisation.Format = format
+		if organisation.TokenFormat != "" {
+			if _, err := domain.ParseTokenFormat(string(organisation.TokenFormat)); err != nil {
+				return nil, fmt.Errorf("organisation %s: %v", organisation.ID, err)
+			}
+		}
+		pseudonymFormat, err := domain.ParsePseudonymFormat(string(organisation.PseudonymFormat))
+		if err != nil {
+			return nil, fmt.Errorf("organisation %s: %v", organisation.ID, err)
+		}
+		organisation.PseudonymFormat = pseudonymFormat
+		r.organisations[organisation.ID] = organisation
+	}
+	return r, nil
+}
+
+// Load reads a registry from a YAML or JSON file with a list of organisations, e.g.
+//
+//	organisations:
+//	  - id: ura:12345678
+//	    name: Ziekenhuis
+//	    scopes: [zorg]
+//	    identifierTypes: [ORGANISATION_PSEUDO]
+//	    depseudonymise: true
+//	    format: base58
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a registry in YAML or JSON, JSON is parsed as a subset of YAML. Unknown fields are rejected, a
+// misspelled field would otherwise leave the scopes or identifier types of an organisation unrestricted.
+func Parse(data []byte) (*Registry, error) {
+	file := registryFile{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid registry: %v", err)
+	}
+	return New(file.Organisations)
+}
+
+// Organisation returns the organisation with the ID.
+func (r *Registry) Organisation(id string) (Organisation, error) {
+	organisation, ok := r.organisations[id]
+	if !ok {
+		return Organisation{}, fmt.Errorf("%w: %s", ErrUnknownOrganisation, id)
+	}
+	return organisation, nil
+}
+
+// Authorise checks that the organisation is in the registry and may use the scope.
+// When an identifier type is given, the organisation must also be allowed to receive identifiers of the type.
+func (r *Registry) Authorise(id string, scope string, identifierType string) error {
+	organisation, err := r.Organisation(id)
+	if err != nil {
+		return err
+	}
+	if !organisation.AllowsScope(scope) {
+		return fmt.Errorf("%w: %s may not use scope %s", ErrNotAllowed, id, scope)
+	}
+	if identifierType != "" && !organisation.AllowsIdentifierType(identifierType) {
+		return fmt.Errorf("%w: %s may not receive %s", ErrNotAllowed, id, identifierType)
+	}
+	return nil
+}
+
+// AuthoriseAdmin checks that the organisation is in the registry and may use the administrative endpoints.
+func (r *Registry) AuthoriseAdmin(id string) error {
+	organisation, err := r.Organisation(id)
+	if err != nil {
+		return err
+	}
+	if !organisation.Admin {
+		return fmt.Errorf("%w: %s is not an administrator", ErrNotAllowed, id)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+)
+
+const testRegistry = `
+organisations:
+  - id: ura:1
+    name: Hospital
+    scopes: [zorg]
+    identifierTypes: [ORGANISATION_PSEUDO]
+    depseudonymise: true
+    admin: true
+  - id: ura:2
+    name: General practitioner
+    scopes: [zorg]
+    format: base58
+    tokenFormat: jwe
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "registry", data: testRegistry},
+		{name: "empty", data: ""},
+		{name: "JSON", data: `{"organisations": [{"id": "ura:1", "scopes": ["zorg"]}]}`},
+		{name: "misspelled scopes", data: "organisations:\n  - id: ura:1\n    scope: [zorg]\n", wantErr: true},
+		{name: "misspelled identifier types", data: "organisations:\n  - id: ura:1\n    identifierType: [ORGANISATION_PSEUDO]\n", wantErr: true},
+		{name: "unknown top-level field", data: "organisation:\n  - id: ura:1\n", wantErr: true},
+		{name: "organisation without id", data: "organisations:\n  - name: Hospital\n", wantErr: true},
+		{name: "duplicate organisation", data: "organisations:\n  - id: ura:1\n  - id: ura:1\n", wantErr: true},
+		{name: "BSN as identifier type", data: "organisations:\n  - id: ura:1\n    identifierTypes: [BSN]\n", wantErr: true},
+		{name: "unknown format", data: "organisations:\n  - id: ura:1\n    format: base32\n", wantErr: true},
+		{name: "unknown token format", data: "organisations:\n  - id: ura:1\n    tokenFormat: saml\n", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry, err := Parse([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", registry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAuthorise(t *testing.T) {
+	registry, err := Parse([]byte(testRegistry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		id             string
+		scope          string
+		identifierType string
+		err            error
+	}{
+		{name: "allowed", id: "ura:1", scope: "zorg", identifierType: "ORGANISATION_PSEUDO"},
+		{name: "depseudonymise", id: "ura:1", scope: "zorg", identifierType: "BSN"},
+		{name: "scope only", id: "ura:2", scope: "zorg"},
+		{name: "scope not allowed", id: "ura:1", scope: "onderzoek", err: ErrNotAllowed},
+		{name: "identifier type not allowed", id: "ura:2", scope: "zorg", identifierType: "ORGANISATION_PSEUDO", err: ErrNotAllowed},
+		{name: "BSN not allowed", id: "ura:2", scope: "zorg", identifierType: "BSN", err: ErrNotAllowed},
+		{name: "unknown organisation", id: "ura:3", scope: "zorg", err: ErrUnknownOrganisation},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := registry.Authorise(test.id, test.scope, test.identifierType)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package replay
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stevenvegt/pseudonyms/expiry"
+	bolt "go.etcd.io/bbolt"
+)
+
+var usedBucket = []byte("used")
+
+// BoltCache is a persistent cache of used tokens in a bbolt database, used tokens are remembered after a restart.
+type BoltCache struct {
+	db    *bolt.DB
+	clock func() time.Time
+}
+
+var _ Cache = (*BoltCache)(nil)
+
+// OpenBoltCache opens the cache in the database file, which is created when it does not exist.
+func OpenBoltCache(path string) (*BoltCache, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replay cache: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(usedBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltCache{db: db, clock: time.Now}, nil
+}
+
+func (c *BoltCache) Use(id string, expiration time.Time) error {
+	now := c.clock()
+	return c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(usedBucket)
+		if value := bucket.Get([]byte(id)); value != nil && now.Before(expiry.DecodeTime(value)) {
+			return ErrReplayed
+		}
+		return bucket.Put([]byte(id), expiry.EncodeTime(expiration))
+	})
+}
+
+// Purge removes the tokens which have expired, they can not be replayed anymore.
+func (c *BoltCache) Purge() error {
+	return expiry.Purge(c.db, usedBucket, c.clock())
+}
+
+// Close closes the database.
+func (c *BoltCache) Close() error {
+	return c.db.Close()
+}
@@ -0,0 +1,90 @@
+// Package replay detects the replay of single-use tokens.
+package replay
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrReplayed is returned when a single-use token has already been used.
+	ErrReplayed = errors.New("token has already been used")
+	// ErrCacheFull is returned when a token can not be remembered because the cache is full.
+	ErrCacheFull = errors.New("replay cache is full")
+)
+
+// Cache remembers the IDs of used tokens until they expire.
+type Cache interface {
+	// Use marks the token as used until its expiration, it returns ErrReplayed when the token has been used before.
+	Use(id string, expiration time.Time) error
+}
+
+type entry struct {
+	id         string
+	expiration time.Time
+}
+
+// expiryHeap is a min-heap of entries by their expiration, the first entry expires first.
+type expiryHeap []entry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiration.Before(h[j].expiration) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(entry)) }
+func (h *expiryHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// MemoryCache is an in-memory cache of used tokens. Used tokens are forgotten after a restart.
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	clock    func() time.Time
+	entries  map[string]time.Time
+	expiries expiryHeap
+}
+
+var _ Cache = (*MemoryCache)(nil)
+
+// NewMemoryCache creates a cache which holds at most capacity tokens. Tokens are forgotten when they expire, when the
+// cache is full with tokens which have not expired new tokens are rejected with ErrCacheFull, as forgetting a token
+// which has not expired would allow it to be replayed.
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		clock:    time.Now,
+		entries:  map[string]time.Time{},
+	}
+}
+
+func (c *MemoryCache) Use(id string, expiration time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock()
+	c.purge(now)
+
+	if _, ok := c.entries[id]; ok {
+		return ErrReplayed
+	}
+	if len(c.entries) >= c.capacity {
+		return ErrCacheFull
+	}
+
+	c.entries[id] = expiration
+	heap.Push(&c.expiries, entry{id: id, expiration: expiration})
+	return nil
+}
+
+// purge forgets the tokens which have expired, in the order of their expiration.
+func (c *MemoryCache) purge(now time.Time) {
+	for len(c.expiries) > 0 && !now.Before(c.expiries[0].expiration) {
+		expired := heap.Pop(&c.expiries).(entry)
+		delete(c.entries, expired.id)
+	}
+}
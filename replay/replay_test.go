@@ -0,0 +1,56 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheReplay(t *testing.T) {
+	cache := NewMemoryCache(10)
+	now := time.Unix(1_700_000_000, 0)
+	cache.clock = func() time.Time { return now }
+
+	if err := cache.Use("a", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Use("a", now.Add(time.Minute)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected ErrReplayed, got %v", err)
+	}
+
+	// an expired token is forgotten, it is rejected by its lifetime instead
+	now = now.Add(time.Minute)
+	if err := cache.Use("a", now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryCacheExpiresShortLivedTokens(t *testing.T) {
+	cache := NewMemoryCache(3)
+	now := time.Unix(1_700_000_000, 0)
+	cache.clock = func() time.Time { return now }
+
+	// a long-lived token which is used first must not keep the short-lived tokens after it in the cache
+	if err := cache.Use("long", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		if err := cache.Use(fmt.Sprintf("short-%d", i), now.Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Use("other", now.Add(time.Hour)); !errors.Is(err, ErrCacheFull) {
+		t.Fatalf("expected ErrCacheFull, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	for i := range 2 {
+		if err := cache.Use(fmt.Sprintf("other-%d", i), now.Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cache.Use("long", now.Add(time.Hour)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected ErrReplayed, got %v", err)
+	}
+}
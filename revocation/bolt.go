@@ -0,0 +1,82 @@
+package revocation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/stevenvegt/pseudonyms/expiry"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	tokensBucket  = []byte("tokens")
+	issuersBucket = []byte("issuers")
+)
+
+// BoltStore keeps the revocations in a bbolt database, they are remembered after a restart.
+type BoltStore struct {
+	db    *bolt.DB
+	clock func() time.Time
+}
+
+var _ Store = (*BoltStore)(nil)
+
+// OpenBoltStore opens the store in the database file, which is created when it does not exist.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open revocation store: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(tokensBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(issuersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db, clock: time.Now}, nil
+}
+
+func (s *BoltStore) RevokeToken(id string, expiration time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokensBucket).Put([]byte(id), expiry.EncodeTime(expiration))
+	})
+}
+
+func (s *BoltStore) RevokeIssuer(issuer string, issuedBefore time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(issuersBucket)
+		if value := bucket.Get([]byte(issuer)); value != nil && !issuedBefore.After(expiry.DecodeTime(value)) {
+			return nil
+		}
+		return bucket.Put([]byte(issuer), expiry.EncodeTime(issuedBefore))
+	})
+}
+
+func (s *BoltStore) Check(id string, issuer string, issuedAt time.Time) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if id != "" && tx.Bucket(tokensBucket).Get([]byte(id)) != nil {
+			return ErrTokenRevoked
+		}
+		if value := tx.Bucket(issuersBucket).Get([]byte(issuer)); value != nil && !issuedAt.After(expiry.DecodeTime(value)) {
+			return ErrIssuerRevoked
+		}
+		return nil
+	})
+}
+
+// Purge removes the revocations of tokens which have expired, they can not be used anymore.
+func (s *BoltStore) Purge() error {
+	return expiry.Purge(s.db, tokensBucket, s.clock())
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,82 @@
+// Package revocation keeps the tokens which have been revoked, individually or for all tokens of their issuer.
+package revocation
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTokenRevoked is returned when the token has been revoked.
+	ErrTokenRevoked = errors.New("token has been revoked")
+	// ErrIssuerRevoked is returned when the token has been revoked with all other tokens of its issuer.
+	ErrIssuerRevoked = errors.New("tokens of issuer have been revoked")
+)
+
+// Store keeps the revocations of tokens and issuers.
+type Store interface {
+	// RevokeToken revokes the token until its expiration, after which it can not be used anyway.
+	RevokeToken(id string, expiration time.Time) error
+	// RevokeIssuer revokes all tokens of the issuer which have been issued at or before the time.
+	RevokeIssuer(issuer string, issuedBefore time.Time) error
+	// Check returns ErrTokenRevoked or ErrIssuerRevoked when the token has been revoked.
+	Check(id string, issuer string, issuedAt time.Time) error
+}
+
+// MemoryStore keeps the revocations in memory, they are forgotten after a restart.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	clock   func() time.Time
+	tokens  map[string]time.Time
+	issuers map[string]time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		clock:   time.Now,
+		tokens:  map[string]time.Time{},
+		issuers: map[string]time.Time{},
+	}
+}
+
+func (s *MemoryStore) RevokeToken(id string, expiration time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// tokens which have expired can not be used anymore, so their revocations are removed
+	now := s.clock()
+	for token, tokenExpiration := range s.tokens {
+		if !now.Before(tokenExpiration) {
+			delete(s.tokens, token)
+		}
+	}
+	s.tokens[id] = expiration
+	return nil
+}
+
+func (s *MemoryStore) RevokeIssuer(issuer string, issuedBefore time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if issuedBefore.After(s.issuers[issuer]) {
+		s.issuers[issuer] = issuedBefore
+	}
+	return nil
+}
+
+func (s *MemoryStore) Check(id string, issuer string, issuedAt time.Time) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[id]; ok && id != "" {
+		return ErrTokenRevoked
+	}
+	if revokedAt, ok := s.issuers[issuer]; ok && !issuedAt.After(revokedAt) {
+		return ErrIssuerRevoked
+	}
+	return nil
+}
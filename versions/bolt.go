@@ -0,0 +1,79 @@
+package versions
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/stevenvegt/pseudonyms/crypto"
+	bolt "go.etcd.io/bbolt"
+)
+
+var versionsBucket = []byte("versions")
+
+// BoltStore keeps the versions in a bbolt database, they are remembered after a restart. The subjects are not
+// stored, only a keyed hash of the subject and audience.
+type BoltStore struct {
+	db         *bolt.DB
+	subjectKey []byte
+}
+
+var _ Store = (*BoltStore)(nil)
+
+// OpenBoltStore opens the store in the database file, which is created when it does not exist.
+// The subject key is used to hash the subjects, the same key must be used every time the store is opened.
+func OpenBoltStore(path string, subjectKey []byte) (*BoltStore, error) {
+	if len(subjectKey) < 16 {
+		return nil, fmt.Errorf("version subject key must be at least 16 bytes")
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open version store: %v", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(versionsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db, subjectKey: subjectKey}, nil
+}
+
+func (s *BoltStore) Current(subject string, audience string) (int32, error) {
+	version := Initial
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if value := tx.Bucket(versionsBucket).Get(s.key(subject, audience)); value != nil {
+			version = int32(binary.BigEndian.Uint32(value))
+		}
+		return nil
+	})
+	return version, err
+}
+
+func (s *BoltStore) Bump(subject string, audience string) (int32, error) {
+	version := Initial
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(versionsBucket)
+		key := s.key(subject, audience)
+		if value := bucket.Get(key); value != nil {
+			version = int32(binary.BigEndian.Uint32(value))
+		}
+		version++
+		return bucket.Put(key, binary.BigEndian.AppendUint32(nil, uint32(version)))
+	})
+	return version, err
+}
+
+// Close closes the database.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) key(subject string, audience string) []byte {
+	return crypto.MAC(s.subjectKey, []byte(audience+"\x00"+subject))
+}
@@ -0,0 +1,54 @@
+// Package versions keeps the current version of the pseudonyms of a subject for an audience. The version is bumped
+// when a pseudonym has been compromised, so the audience gets a new pseudonym for the subject.
+package versions
+
+import (
+	"sync"
+)
+
+// Initial is the version of pseudonyms which have never been bumped.
+const Initial int32 = 1
+
+// Store keeps the versions of the pseudonyms.
+type Store interface {
+	// Current returns the current version of the pseudonyms of the subject for the audience.
+	Current(subject string, audience string) (int32, error)
+	// Bump increments the version of the pseudonyms of the subject for the audience and returns the new version.
+	Bump(subject string, audience string) (int32, error)
+}
+
+// MemoryStore keeps the versions in memory, they are forgotten after a restart.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	versions map[string]int32
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates a store in which every pseudonym has the initial version.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{versions: map[string]int32{}}
+}
+
+func (s *MemoryStore) Current(subject string, audience string) (int32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if version, ok := s.versions[audience+"\x00"+subject]; ok {
+		return version, nil
+	}
+	return Initial, nil
+}
+
+func (s *MemoryStore) Bump(subject string, audience string) (int32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := audience + "\x00" + subject
+	version, ok := s.versions[key]
+	if !ok {
+		version = Initial
+	}
+	s.versions[key] = version + 1
+	return version + 1, nil
+}